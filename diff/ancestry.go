@@ -0,0 +1,50 @@
+// diff/ancestry.go
+package diff
+
+import (
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ancestry describes how the two commits of a diff relate in history.
+type ancestry struct {
+	swapped   bool           // second is an ancestor of first, so they belong the other way round
+	diverged  bool           // neither commit is an ancestor of the other
+	mergeBase *object.Commit // best common ancestor, nil for unrelated histories
+}
+
+// commitAncestry works out the ancestry of first and second. Of several
+// equally good merge bases the one with the lowest hash is picked, so that
+// the result doesn't depend on traversal order.
+func commitAncestry(first, second *object.Commit) (ancestry, error) {
+	if first.Hash == second.Hash {
+		return ancestry{mergeBase: first}, nil
+	}
+
+	isAncestor, err := first.IsAncestor(second)
+	if err != nil {
+		return ancestry{}, err
+	}
+	if isAncestor {
+		return ancestry{mergeBase: first}, nil
+	}
+	isAncestor, err = second.IsAncestor(first)
+	if err != nil {
+		return ancestry{}, err
+	}
+	if isAncestor {
+		return ancestry{swapped: true, mergeBase: second}, nil
+	}
+
+	bases, err := first.MergeBase(second)
+	if err != nil {
+		return ancestry{}, err
+	}
+	result := ancestry{diverged: true}
+	if len(bases) > 0 {
+		sort.Slice(bases, func(i, j int) bool { return bases[i].Hash.String() < bases[j].Hash.String() })
+		result.mergeBase = bases[0]
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+// diff/ancestry_test.go
+package diff
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// branchingHistory builds base -> b -> c on master, feature -> d branching
+// off b and an unrelated root commit e on the orphan branch.
+func branchingHistory(t *testing.T) (r *testRepo, base, b, c, d, e *object.Commit) {
+	t.Helper()
+	r = newTestRepo(t)
+	base = r.commit(map[string]string{"base.txt": "base\n"})
+	b = r.commit(map[string]string{"b.txt": "b\n"})
+	c = r.commit(map[string]string{"c.txt": "c\n"})
+
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Hash: b.Hash, Branch: plumbing.NewBranchReferenceName("feature"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	d = r.commit(map[string]string{"d.txt": "d\n"})
+
+	// An unborn branch makes the next commit a root commit
+	if err := r.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("orphan"))); err != nil {
+		t.Fatal(err)
+	}
+	e = r.commit(map[string]string{"e.txt": "e\n"})
+	return r, base, b, c, d, e
+}
+
+func TestCommitAncestry(t *testing.T) {
+	_, _, b, c, d, e := branchingHistory(t)
+
+	tests := []struct {
+		name          string
+		first, second *object.Commit
+		swapped       bool
+		diverged      bool
+		mergeBase     *object.Commit
+	}{
+		{"ancestor first", b, c, false, false, b},
+		{"descendant first", c, b, true, false, b},
+		{"equal", c, c, false, false, c},
+		{"diverged", c, d, false, true, b},
+		{"diverged reversed", d, c, false, true, b},
+		{"unrelated histories", c, e, false, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := commitAncestry(tt.first, tt.second)
+			if err != nil {
+				t.Fatalf("commitAncestry() error = %v", err)
+			}
+			if got.swapped != tt.swapped || got.diverged != tt.diverged {
+				t.Errorf("commitAncestry() swapped = %v, diverged = %v, want %v, %v", got.swapped, got.diverged, tt.swapped, tt.diverged)
+			}
+			switch {
+			case tt.mergeBase == nil && got.mergeBase != nil:
+				t.Errorf("commitAncestry() merge base = %s, want none", got.mergeBase.Hash)
+			case tt.mergeBase != nil && (got.mergeBase == nil || got.mergeBase.Hash != tt.mergeBase.Hash):
+				t.Errorf("commitAncestry() merge base = %v, want %s", got.mergeBase, tt.mergeBase.Hash)
+			}
+		})
+	}
+}
+
+// changedFiles lists the files of a result by category, sorted.
+func changedFiles(grouped map[string][]string) string {
+	var files []string
+	for _, names := range grouped {
+		files = append(files, names...)
+	}
+	sort.Strings(files)
+	return strings.Join(files, ",")
+}
+
+func TestDiffRevisions(t *testing.T) {
+	r, _, b, c, d, _ := branchingHistory(t)
+
+	tests := []struct {
+		name          string
+		first, second string
+		opts          Options
+		wantFirst     plumbing.Hash
+		wantSecond    plumbing.Hash
+		created       string
+		deleted       string
+		diverged      bool
+	}{
+		{"in order", b.Hash.String(), "master", Options{}, b.Hash, c.Hash, "c.txt", "", false},
+		{"swapped into ancestry order", "master", b.Hash.String(), Options{}, b.Hash, c.Hash, "c.txt", "", false},
+		{"diverged", "master", "feature", Options{}, c.Hash, d.Hash, "d.txt", "c.txt", true},
+		{"merge base", "master", "feature", Options{MergeBase: true}, b.Hash, d.Hash, "d.txt", "", true},
+		{"merge base of an ancestor", b.Hash.String(), "master", Options{MergeBase: true}, b.Hash, c.Hash, "c.txt", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := diffRevisions(r.repo, tt.first, tt.second, tt.opts)
+			if err != nil {
+				t.Fatalf("diffRevisions() error = %v", err)
+			}
+			if result.CommitDetails[0].Hash != tt.wantFirst.String() || result.CommitDetails[1].Hash != tt.wantSecond.String() {
+				t.Errorf("diffRevisions() compared %s..%s, want %s..%s", result.CommitDetails[0].Hash, result.CommitDetails[1].Hash, tt.wantFirst, tt.wantSecond)
+			}
+			if got := changedFiles(result.CreatedFiles); got != tt.created {
+				t.Errorf("created = %q, want %q", got, tt.created)
+			}
+			if got := changedFiles(result.DeletedFiles); got != tt.deleted {
+				t.Errorf("deleted = %q, want %q", got, tt.deleted)
+			}
+			if result.Diverged != tt.diverged || result.MergeBase != b.Hash.String() {
+				t.Errorf("diverged = %v, merge base = %s, want %v, %s", result.Diverged, result.MergeBase, tt.diverged, b.Hash)
+			}
+		})
+	}
+
+	if _, err := diffRevisions(r.repo, "master", "orphan", Options{MergeBase: true}); err == nil || !strings.Contains(err.Error(), "no common ancestor") {
+		t.Errorf("diffRevisions() of unrelated histories error = %v, want no common ancestor", err)
+	}
+}
+
+func TestSplitRange(t *testing.T) {
+	tests := []struct {
+		spec          string
+		first, second string
+		symmetric, ok bool
+	}{
+		{"main..feature", "main", "feature", false, true},
+		{"main...feature", "main", "feature", true, true},
+		{"v1.0..", "v1.0", "HEAD", false, true},
+		{"..feature", "HEAD", "feature", false, true},
+		{"...feature", "HEAD", "feature", true, true},
+		{"main...", "main", "HEAD", true, true},
+		{"HEAD~3..HEAD^", "HEAD~3", "HEAD^", false, true},
+		{"main", "", "", false, false},
+	}
+	for _, tt := range tests {
+		first, second, symmetric, ok := splitRange(tt.spec)
+		if first != tt.first || second != tt.second || symmetric != tt.symmetric || ok != tt.ok {
+			t.Errorf("splitRange(%q) = %q, %q, %v, %v, want %q, %q, %v, %v", tt.spec, first, second, symmetric, ok, tt.first, tt.second, tt.symmetric, tt.ok)
+		}
+	}
+}
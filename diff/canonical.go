@@ -0,0 +1,33 @@
+// diff/canonical.go
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// canonicalJSON encodes v as canonical JSON: compact, with the keys of every
+// object sorted, numbers kept as written and no HTML escaping, so that equal
+// values always encode to the same bytes.
+func canonicalJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Struct fields keep their declaration order; maps are sorted by key
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(generic); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -2,55 +2,167 @@
 package diff
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"mygitapp/fetch"  // Import the fetch package
 	"mygitapp/logger" // Import the logger package
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	git "github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 // CommitDetails holds commit metadata.
 type CommitDetails struct {
-	Hash      string    `json:"hash"`
-	Timestamp time.Time `json:"timestamp"`
+	Spec        string    `json:"spec,omitempty"` // revision as given on the command line
+	Ref         string    `json:"ref,omitempty"`  // full name of the branch or tag the spec resolved through
+	Hash        string    `json:"hash"`
+	Timestamp   time.Time `json:"timestamp"`
+	ResolvedTag string    `json:"resolved_tag,omitempty"` // tag a version selector such as "latest" resolved to
 }
 
 // ComparisonResultGrouped holds the JSON output structure with files grouped under parent folders.
 type ComparisonResultGrouped struct {
 	CommitDetails   [2]CommitDetails    `json:"commit_details"`
+	Diverged        bool                `json:"diverged"`             // neither commit is an ancestor of the other
+	MergeBase       string              `json:"merge_base,omitempty"` // best common ancestor of the commits
 	ModifiedFiles   map[string][]string `json:"modified_files"`
 	CreatedFiles    map[string][]string `json:"created_files"`
 	DeletedFiles    map[string][]string `json:"deleted_files"`
 	UnmodifiedFiles map[string][]string `json:"unmodified_files"`
 	ChangedFolders  []string            `json:"changed_folders"`
+
+	RenamedFiles []RenamedFile `json:"renamed_files"`
+	CopiedFiles  []RenamedFile `json:"copied_files,omitempty"`
+
+	SubmoduleChanges []SubmoduleChange `json:"submodule_changes,omitempty"`
+	LFSChanges       []LFSChange       `json:"lfs_changes,omitempty"`
+
+	FileStats []FileStat `json:"file_stats,omitempty"`
+	Summary   *Summary   `json:"summary,omitempty"`
+
+	Patches []FilePatch `json:"patches,omitempty"`
+
+	filePatches []fdiff.FilePatch // text file patches, for writing a git-style patch
+	pathChanges []PathChange      // every path with its change, for the flat output formats
+}
+
+// Options controls optional behaviour of the diff.
+type Options struct {
+	RecurseSubmodules bool // diff the contents of updated submodules as well as their pointers
+	Prerelease        bool // let version selectors such as "latest" pick pre-release tags
+	NoRenames         bool // report renamed files as deleted and created
+	RenameThreshold   int  // minimum similarity percentage of renames and copies, 50 if zero
+	FindCopies        bool // also detect created files copied from files of the first commit
+	Patch             bool // include the unified diff hunks of every changed file
+	GitPatch          bool // write a git-style patch instead of JSON
+	ContextLines      int  // unchanged lines around each hunk
+	MaxFilePatchBytes int  // omit patches of files larger than this, no limit if zero
+	MaxPatchBytes     int  // omit patches beyond this total size, no limit if zero
+
+	Include []string // doublestar globs of paths to report, all paths if empty
+	Exclude []string // doublestar globs of paths to leave out
+
+	Fast  bool // compare tree entries only, loading blobs only for stats, patches and LFS pointer checks
+	Stats bool // compute line stats in fast mode
+
+	Canonical bool   // print canonical JSON: compact, with sorted keys
+	Format    Format // output format, FormatJSON if empty
+	Layout    Layout // layout of the JSON and YAML output, LayoutGrouped if empty
+
+	MergeBase bool // diff the merge base of the commits against the second commit
 }
 
 // RunDiff runs the diff comparison.
 func RunDiff(args []string) {
-	if len(args) < 2 || len(args) > 3 {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	recurseSubmodules := flags.Bool("recurse-submodules", false, "diff the contents of updated submodules")
+	prerelease := flags.Bool("prerelease", false, "let version selectors pick pre-release tags")
+	noRenames := flags.Bool("no-renames", false, "report renamed files as deleted and created")
+	renameThreshold := flags.Int("rename-threshold", defaultRenameThreshold, "minimum similarity percentage of renames and copies")
+	findCopies := flags.Bool("find-copies", false, "detect files copied from files of the first commit")
+	includePatch := flags.Bool("patch", false, "include unified diff hunks of changed files")
+	gitPatch := flags.Bool("git-patch", false, "write a git-style patch instead of JSON")
+	contextLines := flags.Int("unified", defaultContextLines, "unchanged lines of context around each hunk")
+	maxFilePatch := flags.Int("max-file-patch-bytes", defaultMaxFilePatchBytes, "omit patches of larger files, 0 for no limit")
+	maxPatch := flags.Int("max-patch-bytes", defaultMaxPatchBytes, "omit patches beyond this total size, 0 for no limit")
+	fast := flags.Bool("fast", false, "compare tree entries only, reading no file contents beyond LFS pointer checks")
+	stats := flags.Bool("stat", false, "compute line stats in fast mode")
+	canonical := flags.Bool("canonical", false, "print canonical JSON, compact with sorted keys")
+	format := flags.String("format", "json", "output format: json, yaml, csv, ndjson or text")
+	mergeBase := flags.Bool("merge-base", false, "diff the merge base of the commits against the second commit")
+	layout := flags.String("layout", "grouped", "layout of json and yaml output: grouped, flat or tree")
+	var include, exclude patternList
+	flags.Var(&include, "include", "glob of paths to report (repeatable)")
+	flags.Var(&exclude, "exclude", "glob of paths to leave out (repeatable)")
+
+	err := flags.Parse(args)
+	positional := flags.Args()
+	if len(positional) == 2 {
+		// A single "first..second" range argument stands for both commits
+		if first, second, symmetric, ok := splitRange(positional[1]); ok {
+			positional = []string{positional[0], first, second}
+			*mergeBase = *mergeBase || symmetric
+		}
+	}
+	if err != nil || len(positional) < 2 || len(positional) > 3 || *renameThreshold < 1 || *renameThreshold > 100 || *contextLines < 0 {
 		logger.Log.Error("Invalid arguments for diff")
-		logger.Log.Error("Usage: diff <repository path or remote URI> <first_commit SHA-1> [second_commit SHA-1]")
+		logger.Log.Error("Usage: diff [--recurse-submodules] [--prerelease] [--no-renames] [--rename-threshold percent] [--find-copies] [--fast [--stat]] [--patch | --git-patch] [--unified lines] [--max-file-patch-bytes bytes] [--max-patch-bytes bytes] [--include glob]... [--exclude glob]... [--format json|yaml|csv|ndjson|text] [--layout grouped|flat|tree] [--canonical] [--merge-base] <repository path or remote URI> <first_commit> [second_commit] | <first_commit..second_commit> | <first_commit...second_commit>")
+		os.Exit(1)
+	}
+	outputFormat, err := ParseFormat(*format)
+	if err == nil && outputFormat != FormatJSON && (*canonical || *gitPatch) {
+		err = fmt.Errorf("--canonical and --git-patch only apply to the json format")
+	}
+	outputLayout, layoutErr := ParseLayout(*layout)
+	if err == nil && layoutErr != nil {
+		err = layoutErr
+	} else if err == nil && outputLayout != LayoutGrouped && outputFormat != FormatJSON && outputFormat != FormatYAML {
+		err = fmt.Errorf("--layout only applies to the json and yaml formats")
+	}
+	if err != nil {
+		logger.Log.WithError(err).Error("Invalid arguments for diff")
 		os.Exit(1)
 	}
+	opts := Options{
+		RecurseSubmodules: *recurseSubmodules,
+		Prerelease:        *prerelease,
+		NoRenames:         *noRenames,
+		RenameThreshold:   *renameThreshold,
+		FindCopies:        *findCopies,
+		Patch:             *includePatch,
+		GitPatch:          *gitPatch,
+		ContextLines:      *contextLines,
+		MaxFilePatchBytes: *maxFilePatch,
+		MaxPatchBytes:     *maxPatch,
+		Include:           include,
+		Exclude:           exclude,
+		Fast:              *fast,
+		Stats:             *stats,
+		Canonical:         *canonical,
+		Format:            outputFormat,
+		Layout:            outputLayout,
+		MergeBase:         *mergeBase,
+	}
 
-	repoPathOrURI := args[0]
-	firstCommitSHA := args[1]
-	secondCommitSHA := ""
-	if len(args) == 3 {
-		secondCommitSHA = args[2]
+	repoPathOrURI := positional[0]
+	firstCommitSHA := positional[1]
+	var secondCommitSHA string
+	if len(positional) == 3 {
+		secondCommitSHA = positional[2]
 	}
 
 	var repo *git.Repository
-	var err error
 
-	if isRemoteURI(repoPathOrURI) {
+	if fetch.IsRemoteURL(repoPathOrURI) {
 		// Use fetch package to clone the repository to a temporary directory
 		tempDir, err := os.MkdirTemp("", "git-repo-*")
 		if err != nil {
@@ -60,7 +172,8 @@ func RunDiff(args []string) {
 		defer os.RemoveAll(tempDir)
 
 		logger.Log.Infof("Cloning repository from %s to %s", repoPathOrURI, tempDir)
-		err = fetch.CloneRepository(repoPathOrURI, tempDir)
+		// The diff reads LFS pointers, never the objects behind them
+		_, err = fetch.CloneRepository(repoPathOrURI, tempDir, fetch.Options{Submodules: opts.RecurseSubmodules, SkipLFS: true})
 		if err != nil {
 			logger.Log.WithError(err).Error("Failed to clone repository using fetch package")
 			os.Exit(1)
@@ -81,69 +194,126 @@ func RunDiff(args []string) {
 		}
 	}
 
-	firstCommit, err := getCommit(repo, firstCommitSHA)
+	if secondCommitSHA == "" {
+		// Default to the latest commit on the checked-out (default) branch
+		secondCommitSHA = "HEAD"
+	}
+
+	result, err := diffRevisions(repo, firstCommitSHA, secondCommitSHA, opts)
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to retrieve first_commit")
 		os.Exit(1)
 	}
 
-	if secondCommitSHA == "" {
-		secondCommitSHA, err = getLastCommitSHA(repo)
-		if err != nil {
-			logger.Log.WithError(err).Error("Failed to get the latest commit")
+	if opts.GitPatch {
+		if err := writeGitPatch(os.Stdout, result.filePatches, opts); err != nil {
+			logger.Log.WithError(err).Error("Failed to write patch")
 			os.Exit(1)
 		}
+		logger.Log.Info("Diff operation completed successfully")
+		return
 	}
 
-	secondCommit, err := getCommit(repo, secondCommitSHA)
-	if err != nil {
-		logger.Log.WithError(err).Error("Failed to retrieve second_commit")
+	// Print the result to stdout
+	if err := writeResult(os.Stdout, result, opts); err != nil {
+		logger.Log.WithError(err).Error("Failed to write result")
 		os.Exit(1)
 	}
 
-	// Ensure first_commit is older
-	if firstCommit.Committer.When.After(secondCommit.Committer.When) {
-		firstCommit, secondCommit = secondCommit, firstCommit
-	}
-
-	result := compareCommits(firstCommit, secondCommit)
-	result.CommitDetails[0] = CommitDetails{Hash: firstCommit.Hash.String(), Timestamp: firstCommit.Committer.When}
-	result.CommitDetails[1] = CommitDetails{Hash: secondCommit.Hash.String(), Timestamp: secondCommit.Committer.When}
+	logger.Log.Info("Diff operation completed successfully")
+}
 
-	output, err := json.MarshalIndent(result, "", "  ")
+// diffRevisions resolves two revisions and compares them. The commits are
+// ordered by ancestry rather than by their timestamps; with opts.MergeBase the
+// merge base of the two is compared against the second revision instead.
+func diffRevisions(repo *git.Repository, firstSpec, secondSpec string, opts Options) (ComparisonResultGrouped, error) {
+	firstCommit, firstDetails, err := getCommit(repo, firstSpec, opts)
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to marshal result to JSON")
-		os.Exit(1)
+		logger.Log.WithError(err).Error("Failed to retrieve first_commit")
+		return ComparisonResultGrouped{}, err
+	}
+	secondCommit, secondDetails, err := getCommit(repo, secondSpec, opts)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to retrieve second_commit")
+		return ComparisonResultGrouped{}, err
 	}
 
-	// Print JSON to stdout
-	fmt.Println(string(output))
-
-	logger.Log.Info("Diff operation completed successfully")
-}
+	relation, err := commitAncestry(firstCommit, secondCommit)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to determine commit ancestry")
+		return ComparisonResultGrouped{}, fmt.Errorf("failed to determine commit ancestry: %v", err)
+	}
+	if relation.diverged {
+		logger.Log.Warnf("Commits %s and %s have diverged", firstDetails.Hash, secondDetails.Hash)
+	}
+	if opts.MergeBase {
+		// Three-dot semantics: diff from the merge base to the second commit as given
+		if relation.mergeBase == nil {
+			logger.Log.Error("Commits have no common ancestor")
+			return ComparisonResultGrouped{}, fmt.Errorf("commits %s and %s have no common ancestor", firstDetails.Hash, secondDetails.Hash)
+		}
+		firstCommit = relation.mergeBase
+		firstDetails = CommitDetails{Hash: firstCommit.Hash.String(), Timestamp: firstCommit.Committer.When}
+	} else if relation.swapped {
+		// Ensure first_commit is the ancestor
+		firstCommit, secondCommit = secondCommit, firstCommit
+		firstDetails, secondDetails = secondDetails, firstDetails
+	}
 
-// isRemoteURI checks if the provided path is a remote URI.
-func isRemoteURI(uri string) bool {
-	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "git@")
+	result := compareCommits(repo, firstCommit, secondCommit, opts)
+	result.CommitDetails[0] = firstDetails
+	result.CommitDetails[1] = secondDetails
+	result.Diverged = relation.diverged
+	if relation.mergeBase != nil {
+		result.MergeBase = relation.mergeBase.Hash.String()
+	}
+	return result, nil
 }
 
-// getCommit retrieves the commit object by its SHA-1 hash.
-func getCommit(repo *git.Repository, sha string) (*object.Commit, error) {
-	commitHash := plumbing.NewHash(sha)
-	return repo.CommitObject(commitHash)
+// splitRange splits a "first..second" revision range, or a symmetric
+// "first...second" range that is diffed from the merge base of the two
+// commits. An omitted side defaults to HEAD, as in git.
+func splitRange(spec string) (first, second string, symmetric, ok bool) {
+	first, second, ok = strings.Cut(spec, "..")
+	if !ok {
+		return "", "", false, false
+	}
+	second, symmetric = strings.CutPrefix(second, ".")
+	if first == "" {
+		first = "HEAD"
+	}
+	if second == "" {
+		second = "HEAD"
+	}
+	return first, second, symmetric, true
 }
 
-// getLastCommitSHA gets the SHA of the latest commit on the default branch.
-func getLastCommitSHA(repo *git.Repository) (string, error) {
-	ref, err := repo.Head()
+// getCommit retrieves the commit object a revision (SHA-1 or short SHA, local
+// or remote branch, tag, expression such as HEAD~5, or version selector)
+// resolves to, along with the details recorded for it in the output.
+func getCommit(repo *git.Repository, spec string, opts Options) (*object.Commit, CommitDetails, error) {
+	rev, err := fetch.ResolveSelector(repo, spec, opts.Prerelease)
+	if err != nil {
+		return nil, CommitDetails{}, err
+	}
+	commit, err := repo.CommitObject(rev.Hash)
 	if err != nil {
-		return "", err
+		return nil, CommitDetails{}, err
 	}
-	return ref.Hash().String(), nil
+
+	details := CommitDetails{
+		Spec:      spec,
+		Ref:       rev.Name.String(),
+		Hash:      commit.Hash.String(),
+		Timestamp: commit.Committer.When,
+	}
+	if rev.Type == fetch.RefTag && fetch.IsVersionSelector(spec) {
+		details.ResolvedTag = rev.Name.Short()
+	}
+	return commit, details, nil
 }
 
 // compareCommits analyzes the differences between two commits.
-func compareCommits(firstCommit, secondCommit *object.Commit) ComparisonResultGrouped {
+func compareCommits(repo *git.Repository, firstCommit, secondCommit *object.Commit, opts Options) ComparisonResultGrouped {
 	tree1, err := firstCommit.Tree()
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get tree for first_commit")
@@ -155,11 +325,44 @@ func compareCommits(firstCommit, secondCommit *object.Commit) ComparisonResultGr
 		return ComparisonResultGrouped{}
 	}
 
-	patch, err := tree1.Patch(tree2)
+	// Renames are detected separately, with scores and an adjustable threshold
+	changes, err := object.DiffTreeWithOptions(context.Background(), tree1, tree2, &object.DiffTreeOptions{})
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to create patch between commits")
+		logger.Log.WithError(err).Error("Failed to diff trees between commits")
 		return ComparisonResultGrouped{}
 	}
+	changes = filterChanges(changes, opts)
+
+	// Pair deleted and created files that were moved or copied
+	renamedFiles, copiedFiles, remainingChanges := detectRenames(repo, tree1, changes, opts)
+	if renamedFiles == nil {
+		renamedFiles = []RenamedFile{}
+	}
+
+	// Renames and copies are patched old file against new file
+	relocations := make(map[string]string)
+	for _, renamed := range renamedFiles {
+		relocations[renamed.NewPath] = "renamed"
+		remainingChanges = append(remainingChanges, renamed.change)
+	}
+	for _, copied := range copiedFiles {
+		relocations[copied.NewPath] = "copied"
+		remainingChanges = append(remainingChanges, copied.change)
+	}
+
+	content := needsContent(opts)
+	var filePatches []fdiff.FilePatch
+	if content {
+		patch, err := remainingChanges.Patch()
+		if err != nil {
+			logger.Log.WithError(err).Error("Failed to create patch between commits")
+			return ComparisonResultGrouped{}
+		}
+		filePatches = patch.FilePatches()
+	} else {
+		// Only names are needed: categorize by tree entries without loading blobs
+		filePatches = treeFilePatches(remainingChanges)
+	}
 
 	modifiedFiles := make(map[string][]string)
 	createdFiles := make(map[string][]string)
@@ -167,15 +370,49 @@ func compareCommits(firstCommit, secondCommit *object.Commit) ComparisonResultGr
 	unmodifiedFiles := make(map[string][]string)
 	changedFoldersSet := make(map[string]bool)
 
+	var lfsChanges []LFSChange
+	var patches []FilePatch
+	var textPatches []fdiff.FilePatch
+	var pathChanges []PathChange
+	var fileStats []FileStat
+	if content {
+		fileStats = []FileStat{}
+	}
+	budget := newPatchBudget(opts)
+
 	changedFilesSet := make(map[string]bool)
-	filePatches := patch.FilePatches()
 	for _, filePatch := range filePatches {
 		from, to := filePatch.Files()
-		if from == nil && to != nil {
+		if from == nil && to == nil {
+			// Submodule pointer changes are patched separately below
+			continue
+		}
+		// Report LFS object changes rather than changes of the pointer text
+		lfsChange := lfsChangeFor(repo, from, to)
+		if lfsChange != nil {
+			lfsChanges = append(lfsChanges, *lfsChange)
+		}
+		text := lfsChange == nil
+		if text {
+			textPatches = append(textPatches, filePatch)
+		}
+		if opts.Patch && text {
+			patches = append(patches, filePatchFor(filePatch, opts.ContextLines, budget))
+		}
+		if from != nil && to != nil && from.Path() != to.Path() {
+			// File was renamed or copied; already listed by detectRenames
+			if content && text {
+				fileStats = append(fileStats, fileStatFor(repo, filePatch, relocations[to.Path()]))
+			}
+		} else if from == nil && to != nil {
 			// File was created
 			createdPath := to.Path()
 			parentFolder := getParentFolder(createdPath)
 			createdFiles[parentFolder] = append(createdFiles[parentFolder], filepath.Base(createdPath))
+			pathChanges = append(pathChanges, PathChange{Path: createdPath, Change: "created"})
+			if content && text {
+				fileStats = append(fileStats, fileStatFor(repo, filePatch, "created"))
+			}
 			changedFilesSet[createdPath] = true
 			changedFoldersSet[parentFolder] = true
 		} else if from != nil && to == nil {
@@ -183,6 +420,10 @@ func compareCommits(firstCommit, secondCommit *object.Commit) ComparisonResultGr
 			deletedPath := from.Path()
 			parentFolder := getParentFolder(deletedPath)
 			deletedFiles[parentFolder] = append(deletedFiles[parentFolder], filepath.Base(deletedPath))
+			pathChanges = append(pathChanges, PathChange{Path: deletedPath, Change: "deleted"})
+			if content && text {
+				fileStats = append(fileStats, fileStatFor(repo, filePatch, "deleted"))
+			}
 			changedFilesSet[deletedPath] = true
 			changedFoldersSet[parentFolder] = true
 		} else if from != nil && to != nil {
@@ -190,50 +431,104 @@ func compareCommits(firstCommit, secondCommit *object.Commit) ComparisonResultGr
 			modifiedPath := from.Path()
 			parentFolder := getParentFolder(modifiedPath)
 			modifiedFiles[parentFolder] = append(modifiedFiles[parentFolder], filepath.Base(modifiedPath))
+			pathChanges = append(pathChanges, PathChange{Path: modifiedPath, Change: "modified"})
+			if content && text {
+				fileStats = append(fileStats, fileStatFor(repo, filePatch, "modified"))
+			}
 			changedFilesSet[modifiedPath] = true
 			changedFoldersSet[parentFolder] = true
 		}
 	}
 
-	// Collect all files from both trees
+	for _, renamed := range renamedFiles {
+		changedFilesSet[renamed.OldPath] = true
+		changedFilesSet[renamed.NewPath] = true
+		changedFoldersSet[getParentFolder(renamed.OldPath)] = true
+		changedFoldersSet[getParentFolder(renamed.NewPath)] = true
+		pathChanges = append(pathChanges, PathChange{Path: renamed.NewPath, Change: "renamed", OldPath: renamed.OldPath, Similarity: renamed.Similarity})
+	}
+	// The source of a copy keeps whatever state it has in the second commit
+	for _, copied := range copiedFiles {
+		changedFilesSet[copied.NewPath] = true
+		changedFoldersSet[getParentFolder(copied.NewPath)] = true
+		pathChanges = append(pathChanges, PathChange{Path: copied.NewPath, Change: "copied", OldPath: copied.OldPath, Similarity: copied.Similarity})
+	}
+
+	sortFileStats(fileStats)
+	var summary *Summary
+	if content {
+		summary = summarize(fileStats)
+	}
+
+	// Collect all files from both trees, reading tree objects only
 	allFilesSet := make(map[string]bool)
-	err = tree1.Files().ForEach(func(f *object.File) error {
-		allFilesSet[f.Name] = true
-		return nil
-	})
+	files1, err := treeFiles(tree1)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to iterate over files in first_commit tree")
 		return ComparisonResultGrouped{}
 	}
-	err = tree2.Files().ForEach(func(f *object.File) error {
-		allFilesSet[f.Name] = true
-		return nil
-	})
+	files2, err := treeFiles(tree2)
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to iterate over files in second_commit tree")
 		return ComparisonResultGrouped{}
 	}
+	for _, file := range append(files1, files2...) {
+		allFilesSet[file] = pathMatches(file, opts)
+	}
 
 	// Identify unmodified files
-	for file := range allFilesSet {
-		if !changedFilesSet[file] {
+	for file, matched := range allFilesSet {
+		if matched && !changedFilesSet[file] {
 			parentFolder := getParentFolder(file)
 			unmodifiedFiles[parentFolder] = append(unmodifiedFiles[parentFolder], filepath.Base(file))
+			pathChanges = append(pathChanges, PathChange{Path: file, Change: "unmodified"})
+		}
+	}
+
+	// Submodule (gitlink) pointer changes don't show up as file patches
+	submoduleChanges := collectSubmoduleChanges(repo, changes, opts)
+	for _, change := range submoduleChanges {
+		textPatches = append(textPatches, gitlinkPatch(change))
+		if opts.Patch {
+			patches = append(patches, filePatchFor(gitlinkPatch(change), opts.ContextLines, budget))
 		}
+		changedFoldersSet[getParentFolder(change.Path)] = true
+		pathChanges = append(pathChanges, submodulePathChange(change))
 	}
 
-	// Convert changedFoldersSet to a slice
+	// Convert changedFoldersSet to a sorted slice
 	changedFolders := make([]string, 0, len(changedFoldersSet))
 	for folder := range changedFoldersSet {
 		changedFolders = append(changedFolders, folder)
 	}
+	sort.Strings(changedFolders)
+
+	// Sort everything else too, so that the same commits always give the same output
+	for _, groups := range []map[string][]string{modifiedFiles, createdFiles, deletedFiles, unmodifiedFiles} {
+		for _, files := range groups {
+			sort.Strings(files)
+		}
+	}
+	sort.Slice(patches, func(i, j int) bool { return patches[i].Path < patches[j].Path })
+	sort.Slice(submoduleChanges, func(i, j int) bool { return submoduleChanges[i].Path < submoduleChanges[j].Path })
+	sort.Slice(lfsChanges, func(i, j int) bool { return lfsChanges[i].Path < lfsChanges[j].Path })
+	sortPathChanges(pathChanges)
 
 	return ComparisonResultGrouped{
-		ModifiedFiles:   modifiedFiles,
-		CreatedFiles:    createdFiles,
-		DeletedFiles:    deletedFiles,
-		UnmodifiedFiles: unmodifiedFiles,
-		ChangedFolders:  changedFolders,
+		ModifiedFiles:    modifiedFiles,
+		CreatedFiles:     createdFiles,
+		DeletedFiles:     deletedFiles,
+		UnmodifiedFiles:  unmodifiedFiles,
+		ChangedFolders:   changedFolders,
+		RenamedFiles:     renamedFiles,
+		CopiedFiles:      copiedFiles,
+		FileStats:        fileStats,
+		Summary:          summary,
+		Patches:          patches,
+		filePatches:      textPatches,
+		pathChanges:      pathChanges,
+		SubmoduleChanges: submoduleChanges,
+		LFSChanges:       lfsChanges,
 	}
 }
 
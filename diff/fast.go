@@ -0,0 +1,94 @@
+// diff/fast.go
+package diff
+
+import (
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// needsContent reports whether the diff must load and compare blob contents:
+// always, unless fast mode is on and neither line stats nor patches are wanted.
+func needsContent(opts Options) bool {
+	return !opts.Fast || opts.Stats || opts.Patch || opts.GitPatch
+}
+
+// treeFile is one side of a change, described by its tree entry alone.
+type treeFile struct {
+	entry object.ChangeEntry
+}
+
+func (f treeFile) Hash() plumbing.Hash     { return f.entry.TreeEntry.Hash }
+func (f treeFile) Mode() filemode.FileMode { return f.entry.TreeEntry.Mode }
+func (f treeFile) Path() string            { return f.entry.Name }
+
+// treeFilePatch is a file patch built from tree entries. Without chunks it
+// categorizes a change without loading blob contents.
+type treeFilePatch struct {
+	from, to fdiff.File
+	chunks   []fdiff.Chunk
+}
+
+func (p treeFilePatch) IsBinary() bool                  { return false }
+func (p treeFilePatch) Files() (fdiff.File, fdiff.File) { return p.from, p.to }
+func (p treeFilePatch) Chunks() []fdiff.Chunk           { return p.chunks }
+
+// textChunk is a chunk of a treeFilePatch.
+type textChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c textChunk) Content() string       { return c.content }
+func (c textChunk) Type() fdiff.Operation { return c.op }
+
+// treeFilePatches turns changes into file patches without content. As with
+// content patches, changes involving submodules or other non-file entries
+// are left out.
+func treeFilePatches(changes object.Changes) []fdiff.FilePatch {
+	var filePatches []fdiff.FilePatch
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			continue
+		}
+		var patch treeFilePatch
+		if action == merkletrie.Insert || action == merkletrie.Modify {
+			if !change.To.TreeEntry.Mode.IsFile() {
+				continue
+			}
+			patch.to = treeFile{change.To}
+		}
+		if action == merkletrie.Delete || action == merkletrie.Modify {
+			if !change.From.TreeEntry.Mode.IsFile() {
+				continue
+			}
+			patch.from = treeFile{change.From}
+		}
+		filePatches = append(filePatches, patch)
+	}
+	return filePatches
+}
+
+// treeFiles lists the paths of the files in tree, reading tree objects only.
+func treeFiles(tree *object.Tree) ([]string, error) {
+	var files []string
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.Mode.IsFile() {
+			files = append(files, name)
+		}
+	}
+}
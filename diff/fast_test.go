@@ -0,0 +1,63 @@
+// diff/fast_test.go
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFastModeMatchesContentDiff(t *testing.T) {
+	pointer := func(oidDigit string, size string) string {
+		return "version https://git-lfs.github.com/spec/v1\noid sha256:" + strings.Repeat(oidDigit, 64) + "\nsize " + size + "\n"
+	}
+	r := newTestRepo(t)
+	first := r.commit(map[string]string{
+		"README.md":            "readme\n",
+		"src/main.go":          "package main\n",
+		"src/util/strings.go":  "package util\n",
+		"src/util/numbers.go":  "package util // numbers\n",
+		"docs/guide.md":        "guide\n",
+		"docs/old.md":          "old\n",
+		"assets/logo.png":      pointer("a", "100"),
+		"config/settings.yaml": "debug: false\n",
+	})
+	second := r.commit(map[string]string{
+		"src/main.go":         "package main\n\nfunc main() {}\n",
+		"src/util/numbers.go": "",
+		"src/util/ints.go":    "package util // numbers\n",
+		"docs/old.md":         "",
+		"docs/new/intro.md":   "intro\n",
+		"assets/logo.png":     pointer("b", "200"),
+		"tools/build.sh":      "#!/bin/sh\n",
+	})
+
+	content := compareCommits(r.repo, first, second, Options{})
+	fast := compareCommits(r.repo, first, second, Options{Fast: true})
+
+	for _, field := range []struct {
+		name          string
+		content, fast any
+	}{
+		{"modified_files", content.ModifiedFiles, fast.ModifiedFiles},
+		{"created_files", content.CreatedFiles, fast.CreatedFiles},
+		{"deleted_files", content.DeletedFiles, fast.DeletedFiles},
+		{"unmodified_files", content.UnmodifiedFiles, fast.UnmodifiedFiles},
+		{"changed_folders", content.ChangedFolders, fast.ChangedFolders},
+		{"renamed_files", describeRenames(content.RenamedFiles), describeRenames(fast.RenamedFiles)},
+		{"lfs_changes", content.LFSChanges, fast.LFSChanges},
+	} {
+		if !reflect.DeepEqual(field.content, field.fast) {
+			t.Errorf("%s differ: content %v, fast %v", field.name, field.content, field.fast)
+		}
+	}
+
+	// Guard against both sides being empty
+	if len(content.ModifiedFiles) == 0 || len(content.CreatedFiles) == 0 || len(content.DeletedFiles) == 0 ||
+		len(content.UnmodifiedFiles) == 0 || len(content.RenamedFiles) != 1 || len(content.LFSChanges) != 1 {
+		t.Errorf("content diff = %+v, want every category populated", content)
+	}
+	if fast.FileStats != nil || fast.Summary != nil {
+		t.Errorf("fast diff has file stats %v and summary %v, want none without Stats", fast.FileStats, fast.Summary)
+	}
+}
@@ -0,0 +1,60 @@
+// diff/filter.go
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// patternList collects the values of a repeatable glob flag.
+type patternList []string
+
+func (p *patternList) String() string { return strings.Join(*p, ",") }
+
+func (p *patternList) Set(pattern string) error {
+	if !doublestar.ValidatePattern(pattern) {
+		return fmt.Errorf("invalid glob pattern %q", pattern)
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+// pathMatches reports whether path passes the include and exclude globs of
+// opts: it must match an include glob, if there are any, and no exclude glob.
+func pathMatches(path string, opts Options) bool {
+	if len(opts.Include) > 0 && !matchesAny(path, opts.Include) {
+		return false
+	}
+	return !matchesAny(path, opts.Exclude)
+}
+
+// matchesAny reports whether path matches one of patterns.
+func matchesAny(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filterChanges drops the changes to paths filtered out by opts.
+func filterChanges(changes object.Changes, opts Options) object.Changes {
+	if len(opts.Include) == 0 && len(opts.Exclude) == 0 {
+		return changes
+	}
+	var filtered object.Changes
+	for _, change := range changes {
+		path := change.To.Name
+		if path == "" {
+			path = change.From.Name
+		}
+		if pathMatches(path, opts) {
+			filtered = append(filtered, change)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,202 @@
+// diff/format.go
+package diff
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format of the diff command.
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatYAML   Format = "yaml"
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatText   Format = "text"
+)
+
+// PathChange is a single path with the way it changed, as listed by the flat
+// output formats.
+type PathChange struct {
+	Path       string `json:"path"`
+	Change     string `json:"change"`               // "created", "deleted", "modified", "renamed", "copied" or "unmodified"
+	OldPath    string `json:"old_path,omitempty"`   // source of a rename or copy
+	Similarity int    `json:"similarity,omitempty"` // similarity percentage of a rename or copy
+	Submodule  bool   `json:"submodule,omitempty"`  // the path is a submodule
+}
+
+// ParseFormat parses an output format name. An empty string means FormatJSON.
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatYAML, "yml":
+		return FormatYAML, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON, "jsonl":
+		return FormatNDJSON, nil
+	case FormatText, "name-status":
+		return FormatText, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (expected json, yaml, csv, ndjson or text)", format)
+}
+
+// submodulePathChange describes a submodule pointer change as a path change.
+func submodulePathChange(change SubmoduleChange) PathChange {
+	kinds := map[string]string{"added": "created", "removed": "deleted", "updated": "modified"}
+	return PathChange{Path: change.Path, Change: kinds[change.Change], Submodule: true}
+}
+
+// sortPathChanges orders changes by path, then by change.
+func sortPathChanges(changes []PathChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Change < changes[j].Change
+	})
+}
+
+// writeResult writes result to w in the format and layout of opts.
+func writeResult(w io.Writer, result ComparisonResultGrouped, opts Options) error {
+	switch opts.Format {
+	case FormatYAML:
+		return writeYAML(w, layoutResult(result, opts.Layout))
+	case FormatCSV:
+		return writeCSV(w, result)
+	case FormatNDJSON:
+		return writeNDJSON(w, result)
+	case FormatText:
+		return writeNameStatus(w, result)
+	}
+
+	var output []byte
+	var err error
+	if opts.Canonical {
+		output, err = canonicalJSON(layoutResult(result, opts.Layout))
+	} else {
+		output, err = json.MarshalIndent(layoutResult(result, opts.Layout), "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal result to JSON: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(output))
+	return err
+}
+
+// writeYAML writes result as YAML, with the same field names and order as the JSON output.
+func writeYAML(w io.Writer, result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	// JSON is YAML; decoding it into a node keeps the field order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to convert result to YAML: %v", err)
+	}
+	clearStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// clearStyle switches node and its children from JSON's flow style and
+// double quotes to YAML's default block style.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// writeCSV writes one row per path with its change.
+func writeCSV(w io.Writer, result ComparisonResultGrouped) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"path", "change", "old_path", "similarity", "submodule"}); err != nil {
+		return err
+	}
+	for _, change := range result.pathChanges {
+		similarity := ""
+		if change.Similarity > 0 {
+			similarity = strconv.Itoa(change.Similarity)
+		}
+		record := []string{change.Path, change.Change, change.OldPath, similarity, strconv.FormatBool(change.Submodule)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ndjsonEvent is a line of the NDJSON output: a "commit" event for each of
+// the two commits, a "change" event per path and a closing "summary" event.
+type ndjsonEvent struct {
+	Event string `json:"event"`
+	*CommitDetails
+	*PathChange
+	*Summary
+}
+
+// writeNDJSON writes result as newline-delimited JSON events.
+func writeNDJSON(w io.Writer, result ComparisonResultGrouped) error {
+	events := []ndjsonEvent{
+		{Event: "commit", CommitDetails: &result.CommitDetails[0]},
+		{Event: "commit", CommitDetails: &result.CommitDetails[1]},
+	}
+	for i := range result.pathChanges {
+		events = append(events, ndjsonEvent{Event: "change", PathChange: &result.pathChanges[i]})
+	}
+	if result.Summary != nil {
+		events = append(events, ndjsonEvent{Event: "summary", Summary: result.Summary})
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writeNameStatus writes the changed paths like git diff --name-status.
+func writeNameStatus(w io.Writer, result ComparisonResultGrouped) error {
+	var buf bytes.Buffer
+	for _, change := range result.pathChanges {
+		switch change.Change {
+		case "created":
+			fmt.Fprintf(&buf, "A\t%s\n", change.Path)
+		case "deleted":
+			fmt.Fprintf(&buf, "D\t%s\n", change.Path)
+		case "modified":
+			fmt.Fprintf(&buf, "M\t%s\n", change.Path)
+		case "renamed":
+			fmt.Fprintf(&buf, "R%03d\t%s\t%s\n", change.Similarity, change.OldPath, change.Path)
+		case "copied":
+			fmt.Fprintf(&buf, "C%03d\t%s\t%s\n", change.Similarity, change.OldPath, change.Path)
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,162 @@
+// diff/layout.go
+package diff
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Layout is the shape of the JSON and YAML output of the diff command.
+type Layout string
+
+const (
+	LayoutGrouped Layout = "grouped" // files grouped by parent folder, per change type
+	LayoutFlat    Layout = "flat"    // a single list of full paths with their change
+	LayoutTree    Layout = "tree"    // a nested directory tree with change counts per folder
+)
+
+// ComparisonResultFlat lists every path of the comparison with its change.
+type ComparisonResultFlat struct {
+	CommitDetails [2]CommitDetails `json:"commit_details"`
+	Diverged      bool             `json:"diverged"`
+	MergeBase     string           `json:"merge_base,omitempty"`
+	Files         []PathChange     `json:"files"`
+
+	FileStats        []FileStat        `json:"file_stats,omitempty"`
+	Summary          *Summary          `json:"summary,omitempty"`
+	Patches          []FilePatch       `json:"patches,omitempty"`
+	SubmoduleChanges []SubmoduleChange `json:"submodule_changes,omitempty"`
+	LFSChanges       []LFSChange       `json:"lfs_changes,omitempty"`
+}
+
+// ComparisonResultTree nests the paths of the comparison in a directory tree.
+type ComparisonResultTree struct {
+	CommitDetails [2]CommitDetails `json:"commit_details"`
+	Diverged      bool             `json:"diverged"`
+	MergeBase     string           `json:"merge_base,omitempty"`
+	Tree          *TreeNode        `json:"tree"`
+
+	FileStats        []FileStat        `json:"file_stats,omitempty"`
+	Summary          *Summary          `json:"summary,omitempty"`
+	Patches          []FilePatch       `json:"patches,omitempty"`
+	SubmoduleChanges []SubmoduleChange `json:"submodule_changes,omitempty"`
+	LFSChanges       []LFSChange       `json:"lfs_changes,omitempty"`
+}
+
+// TreeNode is a folder or a file of the directory tree layout. The root
+// folder has an empty name and path.
+type TreeNode struct {
+	Name       string         `json:"name"`
+	Path       string         `json:"path"`
+	Change     string         `json:"change,omitempty"`     // files only
+	OldPath    string         `json:"old_path,omitempty"`   // files only, source of a rename or copy
+	Similarity int            `json:"similarity,omitempty"` // files only, for renames and copies
+	Submodule  bool           `json:"submodule,omitempty"`  // files only
+	Counts     map[string]int `json:"counts,omitempty"`     // folders only, files below per change type
+	Children   []*TreeNode    `json:"children,omitempty"`   // folders only, sorted by name
+}
+
+// ParseLayout parses an output layout name. An empty string means LayoutGrouped.
+func ParseLayout(layout string) (Layout, error) {
+	switch Layout(strings.ToLower(layout)) {
+	case "", LayoutGrouped:
+		return LayoutGrouped, nil
+	case LayoutFlat:
+		return LayoutFlat, nil
+	case LayoutTree:
+		return LayoutTree, nil
+	}
+	return "", fmt.Errorf("unknown output layout %q (expected grouped, flat or tree)", layout)
+}
+
+// layoutResult returns result in layout.
+func layoutResult(result ComparisonResultGrouped, layout Layout) any {
+	switch layout {
+	case LayoutFlat:
+		files := result.pathChanges
+		if files == nil {
+			files = []PathChange{}
+		}
+		return ComparisonResultFlat{
+			CommitDetails:    result.CommitDetails,
+			Diverged:         result.Diverged,
+			MergeBase:        result.MergeBase,
+			Files:            files,
+			FileStats:        result.FileStats,
+			Summary:          result.Summary,
+			Patches:          result.Patches,
+			SubmoduleChanges: result.SubmoduleChanges,
+			LFSChanges:       result.LFSChanges,
+		}
+	case LayoutTree:
+		return ComparisonResultTree{
+			CommitDetails:    result.CommitDetails,
+			Diverged:         result.Diverged,
+			MergeBase:        result.MergeBase,
+			Tree:             buildTree(result.pathChanges),
+			FileStats:        result.FileStats,
+			Summary:          result.Summary,
+			Patches:          result.Patches,
+			SubmoduleChanges: result.SubmoduleChanges,
+			LFSChanges:       result.LFSChanges,
+		}
+	}
+	return result
+}
+
+// buildTree nests changes in a directory tree, counting the changes below
+// every folder.
+func buildTree(changes []PathChange) *TreeNode {
+	root := &TreeNode{Counts: make(map[string]int)}
+	folders := map[string]*TreeNode{"": root}
+
+	var folderFor func(dir string) *TreeNode
+	folderFor = func(dir string) *TreeNode {
+		if folder, ok := folders[dir]; ok {
+			return folder
+		}
+		parent := folderFor(parentDir(dir))
+		folder := &TreeNode{Name: path.Base(dir), Path: dir, Counts: make(map[string]int)}
+		parent.Children = append(parent.Children, folder)
+		folders[dir] = folder
+		return folder
+	}
+
+	for _, change := range changes {
+		dir := parentDir(change.Path)
+		folder := folderFor(dir)
+		folder.Children = append(folder.Children, &TreeNode{
+			Name:       path.Base(change.Path),
+			Path:       change.Path,
+			Change:     change.Change,
+			OldPath:    change.OldPath,
+			Similarity: change.Similarity,
+			Submodule:  change.Submodule,
+		})
+
+		// Count the change in every folder up to the root
+		for {
+			folders[dir].Counts[change.Change]++
+			if dir == "" {
+				break
+			}
+			dir = parentDir(dir)
+		}
+	}
+
+	for _, folder := range folders {
+		sort.Slice(folder.Children, func(i, j int) bool { return folder.Children[i].Name < folder.Children[j].Name })
+	}
+	return root
+}
+
+// parentDir returns the folder containing p, or "" at the top level.
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
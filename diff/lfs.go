@@ -0,0 +1,69 @@
+// diff/lfs.go
+package diff
+
+import (
+	"mygitapp/fetch"
+
+	git "github.com/go-git/go-git/v5"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// LFSChange describes a change of the Git LFS object behind a pointer file.
+type LFSChange struct {
+	Path    string `json:"path"`
+	Change  string `json:"change"` // "created", "deleted" or "modified"
+	OldOID  string `json:"old_oid,omitempty"`
+	OldSize int64  `json:"old_size,omitempty"`
+	NewOID  string `json:"new_oid,omitempty"`
+	NewSize int64  `json:"new_size,omitempty"`
+}
+
+// lfsChangeFor reports the LFS object change of a file patch, or nil if neither
+// side of the patch is an LFS pointer.
+func lfsChangeFor(repo *git.Repository, from, to fdiff.File) *LFSChange {
+	oldPointer := lfsPointerOf(repo, from)
+	newPointer := lfsPointerOf(repo, to)
+	if oldPointer == nil && newPointer == nil {
+		return nil
+	}
+
+	change := &LFSChange{Change: "modified"}
+	if from != nil {
+		change.Path = from.Path()
+	}
+	if to != nil {
+		change.Path = to.Path()
+	}
+	switch {
+	case from == nil:
+		change.Change = "created"
+	case to == nil:
+		change.Change = "deleted"
+	}
+	if oldPointer != nil {
+		change.OldOID, change.OldSize = oldPointer.OID, oldPointer.Size
+	}
+	if newPointer != nil {
+		change.NewOID, change.NewSize = newPointer.OID, newPointer.Size
+	}
+	return change
+}
+
+// lfsPointerOf returns the LFS pointer stored in the blob of file, if any.
+func lfsPointerOf(repo *git.Repository, file fdiff.File) *fetch.LFSPointer {
+	if file == nil {
+		return nil
+	}
+	if size, err := repo.Storer.EncodedObjectSize(file.Hash()); err != nil || size >= fetch.LFSMaxPointerSize {
+		return nil
+	}
+	blob, err := repo.BlobObject(file.Hash())
+	if err != nil {
+		return nil
+	}
+	pointer, ok := fetch.BlobLFSPointer(blob)
+	if !ok {
+		return nil
+	}
+	return pointer
+}
@@ -0,0 +1,224 @@
+// diff/patch.go
+package diff
+
+import (
+	"bytes"
+	"io"
+	"mygitapp/logger"
+	"sort"
+	"strings"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+const (
+	defaultContextLines      = 3
+	defaultMaxFilePatchBytes = 256 << 10 // 256 KiB
+	defaultMaxPatchBytes     = 4 << 20   // 4 MiB
+	noNewlineMarker          = `\ No newline at end of file`
+)
+
+// FilePatch holds the unified diff hunks of a single file.
+type FilePatch struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"` // source of a rename or copy
+	Binary  bool   `json:"binary,omitempty"`
+	Omitted bool   `json:"omitted,omitempty"` // hunks left out for exceeding a size limit
+	Hunks   []Hunk `json:"hunks"`
+}
+
+// Hunk is a unified diff hunk. Lines start with ' ', '+' or '-', as in git.
+type Hunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// patchLine is a single line of a file patch.
+type patchLine struct {
+	op   fdiff.Operation
+	text string
+	eol  bool // the line ends with a newline
+}
+
+// patchBudget enforces the per-file and total patch size limits. A limit of
+// zero or less means no limit.
+type patchBudget struct {
+	maxFile  int
+	maxTotal int
+	used     int
+}
+
+// newPatchBudget returns the patch budget of opts.
+func newPatchBudget(opts Options) *patchBudget {
+	return &patchBudget{maxFile: opts.MaxFilePatchBytes, maxTotal: opts.MaxPatchBytes}
+}
+
+// take reports whether a file patch of size bytes still fits, and counts it if so.
+func (b *patchBudget) take(size int) bool {
+	if b.maxFile > 0 && size > b.maxFile {
+		return false
+	}
+	if b.maxTotal > 0 && b.used+size > b.maxTotal {
+		return false
+	}
+	b.used += size
+	return true
+}
+
+// filePatchFor builds the hunks of filePatch with the given number of context lines.
+func filePatchFor(filePatch fdiff.FilePatch, contextLines int, budget *patchBudget) FilePatch {
+	from, to := filePatch.Files()
+	result := FilePatch{Binary: filePatch.IsBinary(), Hunks: []Hunk{}}
+	if from != nil {
+		result.Path = from.Path()
+	}
+	if to != nil {
+		if from != nil && from.Path() != to.Path() {
+			result.OldPath = from.Path()
+		}
+		result.Path = to.Path()
+	}
+	if result.Binary {
+		return result
+	}
+
+	hunks := buildHunks(patchLines(filePatch.Chunks()), contextLines)
+	size := 0
+	for _, hunk := range hunks {
+		for _, line := range hunk.Lines {
+			size += len(line) + 1
+		}
+	}
+	if !budget.take(size) {
+		logger.Log.Warnf("Omitting patch of %s: %d bytes exceed the patch size limit", result.Path, size)
+		result.Omitted = true
+		return result
+	}
+	result.Hunks = hunks
+	return result
+}
+
+// patchLines splits the chunks of a file patch into lines.
+func patchLines(chunks []fdiff.Chunk) []patchLine {
+	var lines []patchLine
+	for _, chunk := range chunks {
+		content := chunk.Content()
+		for content != "" {
+			text, rest, eol := strings.Cut(content, "\n")
+			lines = append(lines, patchLine{op: chunk.Type(), text: text, eol: eol})
+			content = rest
+		}
+	}
+	return lines
+}
+
+// buildHunks groups changed lines into hunks with up to contextLines
+// unchanged lines around them, merging hunks whose context would overlap.
+func buildHunks(lines []patchLine, contextLines int) []Hunk {
+	var changed []int
+	for i, line := range lines {
+		if line.op != fdiff.Equal {
+			changed = append(changed, i)
+		}
+	}
+
+	hunks := []Hunk{}
+	for len(changed) > 0 {
+		// Extend the hunk while the gap to the next change fits in its context
+		first, last := changed[0], changed[0]
+		changed = changed[1:]
+		for len(changed) > 0 && changed[0]-last-1 <= 2*contextLines {
+			last = changed[0]
+			changed = changed[1:]
+		}
+		start := max(first-contextLines, 0)
+		end := min(last+contextLines+1, len(lines))
+		hunks = append(hunks, newHunk(lines, start, end))
+	}
+	return hunks
+}
+
+// newHunk returns the hunk of lines[start:end].
+func newHunk(lines []patchLine, start, end int) Hunk {
+	// Count the old and new lines before the hunk
+	var hunk Hunk
+	for _, line := range lines[:start] {
+		if line.op != fdiff.Add {
+			hunk.OldStart++
+		}
+		if line.op != fdiff.Delete {
+			hunk.NewStart++
+		}
+	}
+
+	for _, line := range lines[start:end] {
+		prefix := " "
+		switch line.op {
+		case fdiff.Add:
+			prefix = "+"
+			hunk.NewLines++
+		case fdiff.Delete:
+			prefix = "-"
+			hunk.OldLines++
+		default:
+			hunk.OldLines++
+			hunk.NewLines++
+		}
+		hunk.Lines = append(hunk.Lines, prefix+line.text)
+		if !line.eol {
+			hunk.Lines = append(hunk.Lines, noNewlineMarker)
+		}
+	}
+
+	// Hunks that are empty on one side start at the line before them, as in git
+	if hunk.OldLines > 0 {
+		hunk.OldStart++
+	}
+	if hunk.NewLines > 0 {
+		hunk.NewStart++
+	}
+	return hunk
+}
+
+// gitPatch is a patch of a selection of file patches.
+type gitPatch []fdiff.FilePatch
+
+func (p gitPatch) FilePatches() []fdiff.FilePatch { return p }
+func (p gitPatch) Message() string                { return "" }
+
+// writeGitPatch writes filePatches to w as a git-style patch stream, sorted
+// by path. Files whose patch exceeds the size limits are left out.
+func writeGitPatch(w io.Writer, filePatches []fdiff.FilePatch, opts Options) error {
+	sort.Slice(filePatches, func(i, j int) bool {
+		return filePatchPath(filePatches[i]) < filePatchPath(filePatches[j])
+	})
+
+	budget := newPatchBudget(opts)
+	for _, filePatch := range filePatches {
+		var buf bytes.Buffer
+		if err := fdiff.NewUnifiedEncoder(&buf, opts.ContextLines).Encode(gitPatch{filePatch}); err != nil {
+			return err
+		}
+		if !budget.take(buf.Len()) {
+			logger.Log.Warnf("Omitting patch of %s: %d bytes exceed the patch size limit", filePatchPath(filePatch), buf.Len())
+			continue
+		}
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filePatchPath returns the path of the new file of filePatch, or of the old
+// file if it was deleted.
+func filePatchPath(filePatch fdiff.FilePatch) string {
+	from, to := filePatch.Files()
+	if to != nil {
+		return to.Path()
+	}
+	return from.Path()
+}
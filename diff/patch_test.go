@@ -0,0 +1,176 @@
+// diff/patch_test.go
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// linesOf builds patch lines from ops such as "=1", "-2" and "+2", where the
+// text after the operation is the line content.
+func linesOf(specs ...string) []patchLine {
+	ops := map[byte]fdiff.Operation{'=': fdiff.Equal, '-': fdiff.Delete, '+': fdiff.Add}
+	var lines []patchLine
+	for _, spec := range specs {
+		lines = append(lines, patchLine{op: ops[spec[0]], text: spec[1:], eol: true})
+	}
+	return lines
+}
+
+// equalRange returns "=<i>" specs for the lines from to to.
+func equalRange(from, to int) []string {
+	var specs []string
+	for i := from; i <= to; i++ {
+		specs = append(specs, fmt.Sprintf("=%d", i))
+	}
+	return specs
+}
+
+func TestBuildHunks(t *testing.T) {
+	concat := func(parts ...[]string) []string {
+		var all []string
+		for _, part := range parts {
+			all = append(all, part...)
+		}
+		return all
+	}
+	noEOL := linesOf("-a", "+b")
+	noEOL[0].eol, noEOL[1].eol = false, false
+
+	tests := []struct {
+		name         string
+		lines        []patchLine
+		contextLines int
+		want         []Hunk
+	}{
+		{
+			name:         "no changes",
+			lines:        linesOf(equalRange(1, 5)...),
+			contextLines: 3,
+			want:         []Hunk{},
+		},
+		{
+			name:         "change in the middle",
+			lines:        linesOf(concat(equalRange(1, 4), []string{"-5", "+5x"}, equalRange(6, 10))...),
+			contextLines: 3,
+			want: []Hunk{{OldStart: 2, OldLines: 7, NewStart: 2, NewLines: 7,
+				Lines: []string{" 2", " 3", " 4", "-5", "+5x", " 6", " 7", " 8"}}},
+		},
+		{
+			name:         "distant changes",
+			lines:        linesOf(concat([]string{"=1", "-2", "+2x"}, equalRange(3, 17), []string{"-18", "+18x"}, equalRange(19, 20))...),
+			contextLines: 3,
+			want: []Hunk{
+				{OldStart: 1, OldLines: 5, NewStart: 1, NewLines: 5, Lines: []string{" 1", "-2", "+2x", " 3", " 4", " 5"}},
+				{OldStart: 15, OldLines: 6, NewStart: 15, NewLines: 6, Lines: []string{" 15", " 16", " 17", "-18", "+18x", " 19", " 20"}},
+			},
+		},
+		{
+			name:         "overlapping context merges hunks",
+			lines:        linesOf(concat([]string{"-1"}, equalRange(2, 3), []string{"+4"}, equalRange(5, 8))...),
+			contextLines: 1,
+			want:         []Hunk{{OldStart: 1, OldLines: 4, NewStart: 1, NewLines: 4, Lines: []string{"-1", " 2", " 3", "+4", " 5"}}},
+		},
+		{
+			name:         "gap wider than the context splits hunks",
+			lines:        linesOf(concat([]string{"-1"}, equalRange(2, 4), []string{"+5"})...),
+			contextLines: 1,
+			want: []Hunk{
+				{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 1, Lines: []string{"-1", " 2"}},
+				{OldStart: 4, OldLines: 1, NewStart: 3, NewLines: 2, Lines: []string{" 4", "+5"}},
+			},
+		},
+		{
+			name:         "addition without context",
+			lines:        linesOf("=1", "=2", "+3"),
+			contextLines: 0,
+			want:         []Hunk{{OldStart: 2, OldLines: 0, NewStart: 3, NewLines: 1, Lines: []string{"+3"}}},
+		},
+		{
+			name:         "deletion without context",
+			lines:        linesOf("=1", "-2", "=3"),
+			contextLines: 0,
+			want:         []Hunk{{OldStart: 2, OldLines: 1, NewStart: 1, NewLines: 0, Lines: []string{"-2"}}},
+		},
+		{
+			name:         "new file",
+			lines:        linesOf("+1", "+2"),
+			contextLines: 3,
+			want:         []Hunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 2, Lines: []string{"+1", "+2"}}},
+		},
+		{
+			name:         "missing final newline",
+			lines:        noEOL,
+			contextLines: 3,
+			want: []Hunk{{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1,
+				Lines: []string{"-a", noNewlineMarker, "+b", noNewlineMarker}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildHunks(tt.lines, tt.contextLines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildHunks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchLines(t *testing.T) {
+	chunks := []fdiff.Chunk{
+		textChunk{content: "a\nb\n", op: fdiff.Equal},
+		textChunk{content: "c\n", op: fdiff.Delete},
+		textChunk{content: "d", op: fdiff.Add},
+	}
+	want := []patchLine{
+		{op: fdiff.Equal, text: "a", eol: true},
+		{op: fdiff.Equal, text: "b", eol: true},
+		{op: fdiff.Delete, text: "c", eol: true},
+		{op: fdiff.Add, text: "d", eol: false},
+	}
+	if got := patchLines(chunks); !reflect.DeepEqual(got, want) {
+		t.Errorf("patchLines() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPatchBudget(t *testing.T) {
+	budget := &patchBudget{maxFile: 100, maxTotal: 250}
+	for i, tt := range []struct {
+		size int
+		want bool
+	}{
+		{101, false}, // over the per-file limit
+		{100, true},
+		{100, true},
+		{60, false}, // would exceed the total
+		{50, true},
+		{1, false},
+	} {
+		if got := budget.take(tt.size); got != tt.want {
+			t.Errorf("take #%d (%d bytes) = %v, want %v", i, tt.size, got, tt.want)
+		}
+	}
+
+	unlimited := &patchBudget{}
+	if !unlimited.take(1 << 30) {
+		t.Errorf("take() with no limits = false")
+	}
+}
+
+func TestComparePatches(t *testing.T) {
+	r := newTestRepo(t)
+	first := r.commit(map[string]string{"a.txt": "1\n2\n3\n", "gone.txt": "bye\n"})
+	second := r.commit(map[string]string{"a.txt": "1\n2x\n3\n", "gone.txt": "", "new.txt": "hi\n"})
+
+	result := compareCommits(r.repo, first, second, Options{Patch: true, ContextLines: 1})
+	want := []FilePatch{
+		{Path: "a.txt", Hunks: []Hunk{{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Lines: []string{" 1", "-2", "+2x", " 3"}}}},
+		{Path: "gone.txt", Hunks: []Hunk{{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0, Lines: []string{"-bye"}}}},
+		{Path: "new.txt", Hunks: []Hunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []string{"+hi"}}}},
+	}
+	if !reflect.DeepEqual(result.Patches, want) {
+		t.Errorf("Patches = %+v, want %+v", result.Patches, want)
+	}
+}
@@ -0,0 +1,273 @@
+// diff/rename.go
+package diff
+
+import (
+	"bytes"
+	"io"
+	"mygitapp/logger"
+	"sort"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+const (
+	// defaultRenameThreshold is the minimum similarity, in percent, of a rename or copy.
+	defaultRenameThreshold = 50
+	// renameLimit caps the number of created and deleted files compared by content.
+	// Larger diffs only detect exact renames, like git's diff.renameLimit.
+	renameLimit = 1000
+)
+
+// RenamedFile describes a file moved or copied between two commits.
+type RenamedFile struct {
+	OldPath    string `json:"old_path"`
+	NewPath    string `json:"new_path"`
+	Similarity int    `json:"similarity"` // percentage of content shared by the old and new file
+
+	change *object.Change // old and new file, for patching
+}
+
+// candidate is a file taking part in rename detection.
+type candidate struct {
+	path  string
+	hash  plumbing.Hash
+	size  int64
+	entry object.ChangeEntry
+}
+
+// detectRenames pairs deleted files with created files whose content is at
+// least opts.RenameThreshold percent similar and, with opts.FindCopies, pairs
+// the remaining created files with similar files of the first commit. It
+// returns the renames and copies found and the changes left unpaired.
+func detectRenames(repo *git.Repository, tree1 *object.Tree, changes object.Changes, opts Options) ([]RenamedFile, []RenamedFile, object.Changes) {
+	if opts.NoRenames {
+		return nil, nil, changes
+	}
+	threshold := opts.RenameThreshold
+	if threshold <= 0 {
+		threshold = defaultRenameThreshold
+	}
+
+	var deleted, created []candidate
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			continue
+		}
+		switch {
+		case action == merkletrie.Delete && change.From.TreeEntry.Mode != filemode.Submodule:
+			deleted = append(deleted, candidateFor(repo, change.From))
+		case action == merkletrie.Insert && change.To.TreeEntry.Mode != filemode.Submodule:
+			created = append(created, candidateFor(repo, change.To))
+		}
+	}
+
+	// Fast mode pairs identical files only, without loading blob contents
+	var contents *blobCache
+	if !opts.Fast {
+		contents = newBlobCache(repo)
+	}
+	renames, matched := pairCandidates(deleted, created, threshold, contents, false)
+	paired := make(map[string]bool)
+	for _, rename := range renames {
+		paired[rename.OldPath] = true
+		paired[rename.NewPath] = true
+	}
+
+	var copies []RenamedFile
+	if opts.FindCopies {
+		var unmatched []candidate
+		for _, c := range created {
+			if !matched[c.path] {
+				unmatched = append(unmatched, c)
+			}
+		}
+		if len(unmatched) > 0 {
+			sources, err := treeCandidates(tree1, opts)
+			if err != nil {
+				logger.Log.WithError(err).Error("Failed to list copy sources")
+			} else {
+				// A source can be copied to any number of files
+				copies, _ = pairCandidates(sources, unmatched, threshold, contents, true)
+				for _, c := range copies {
+					paired[c.NewPath] = true
+				}
+			}
+		}
+	}
+
+	var remaining object.Changes
+	for _, change := range changes {
+		action, err := change.Action()
+		if err == nil && ((action == merkletrie.Delete && paired[change.From.Name]) || (action == merkletrie.Insert && paired[change.To.Name])) {
+			continue
+		}
+		remaining = append(remaining, change)
+	}
+	return renames, copies, remaining
+}
+
+// candidateFor describes one side of a change as a rename candidate.
+func candidateFor(repo *git.Repository, entry object.ChangeEntry) candidate {
+	c := candidate{path: entry.Name, hash: entry.TreeEntry.Hash, entry: entry}
+	if size, err := repo.Storer.EncodedObjectSize(entry.TreeEntry.Hash); err == nil {
+		c.size = size
+	}
+	return c
+}
+
+// treeCandidates lists the files of tree that pass the path filters of opts
+// as copy sources.
+func treeCandidates(tree *object.Tree, opts Options) ([]candidate, error) {
+	var sources []candidate
+	err := tree.Files().ForEach(func(f *object.File) error {
+		if !pathMatches(f.Name, opts) {
+			return nil
+		}
+		entry := object.ChangeEntry{Name: f.Name, Tree: tree, TreeEntry: object.TreeEntry{Name: f.Name, Mode: f.Mode, Hash: f.Hash}}
+		sources = append(sources, candidate{path: f.Name, hash: f.Hash, size: f.Size, entry: entry})
+		return nil
+	})
+	return sources, err
+}
+
+// pairCandidates matches each target with at most one source, exact content
+// matches first and then the most similar pairs at or above threshold. Each
+// source is used once, unless reuseSources is set as for copies; the returned
+// set holds the paths of the matched targets.
+func pairCandidates(sources, targets []candidate, threshold int, contents *blobCache, reuseSources bool) ([]RenamedFile, map[string]bool) {
+	var pairs []RenamedFile
+	usedSources := make(map[string]bool)
+	matched := make(map[string]bool)
+
+	// Exact matches need no content comparison
+	byHash := make(map[plumbing.Hash][]candidate)
+	for _, source := range sources {
+		byHash[source.hash] = append(byHash[source.hash], source)
+	}
+	for _, target := range targets {
+		for _, source := range byHash[target.hash] {
+			if !usedSources[source.path] {
+				pairs = append(pairs, newRenamedFile(source, target, 100))
+				usedSources[source.path] = !reuseSources
+				matched[target.path] = true
+				break
+			}
+		}
+	}
+
+	if contents == nil {
+		return sortRenames(pairs), matched
+	}
+	if len(sources) > renameLimit || len(targets) > renameLimit {
+		logger.Log.Warnf("Too many files for inexact rename detection (limit %d), only exact renames are reported", renameLimit)
+		return sortRenames(pairs), matched
+	}
+
+	// Score every remaining pair that could reach the threshold
+	var scored []RenamedFile
+	for _, target := range targets {
+		if matched[target.path] {
+			continue
+		}
+		for _, source := range sources {
+			if usedSources[source.path] || !sizesCompatible(source.size, target.size, threshold) {
+				continue
+			}
+			score := similarity(contents.get(source.hash), contents.get(target.hash))
+			if score >= threshold {
+				scored = append(scored, newRenamedFile(source, target, score))
+			}
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Similarity != scored[j].Similarity {
+			return scored[i].Similarity > scored[j].Similarity
+		}
+		if scored[i].NewPath != scored[j].NewPath {
+			return scored[i].NewPath < scored[j].NewPath
+		}
+		return scored[i].OldPath < scored[j].OldPath
+	})
+	for _, pair := range scored {
+		if usedSources[pair.OldPath] || matched[pair.NewPath] {
+			continue
+		}
+		pairs = append(pairs, pair)
+		usedSources[pair.OldPath] = !reuseSources
+		matched[pair.NewPath] = true
+	}
+	return sortRenames(pairs), matched
+}
+
+// newRenamedFile pairs source and target with the given similarity.
+func newRenamedFile(source, target candidate, score int) RenamedFile {
+	return RenamedFile{
+		OldPath:    source.path,
+		NewPath:    target.path,
+		Similarity: score,
+		change:     &object.Change{From: source.entry, To: target.entry},
+	}
+}
+
+// sortRenames orders renames by new path.
+func sortRenames(renames []RenamedFile) []RenamedFile {
+	sort.Slice(renames, func(i, j int) bool { return renames[i].NewPath < renames[j].NewPath })
+	return renames
+}
+
+// sizesCompatible reports whether files of these sizes can be threshold percent similar.
+func sizesCompatible(a, b int64, threshold int) bool {
+	small, large := min(a, b), max(a, b)
+	return large == 0 || small*100 >= large*int64(threshold)
+}
+
+// similarity scores how much of two contents is shared, as the percentage of
+// the larger one made up of lines that also occur in the other.
+func similarity(a, b []byte) int {
+	if len(a) == 0 && len(b) == 0 {
+		return 100
+	}
+	lines := make(map[string]int)
+	for _, line := range bytes.SplitAfter(a, []byte("\n")) {
+		lines[string(line)]++
+	}
+	common := 0
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if lines[string(line)] > 0 {
+			lines[string(line)]--
+			common += len(line)
+		}
+	}
+	return common * 100 / max(len(a), len(b))
+}
+
+// blobCache loads blob contents once for repeated comparisons.
+type blobCache struct {
+	repo  *git.Repository
+	blobs map[plumbing.Hash][]byte
+}
+
+func newBlobCache(repo *git.Repository) *blobCache {
+	return &blobCache{repo: repo, blobs: make(map[plumbing.Hash][]byte)}
+}
+
+// get returns the contents of the blob with the given hash, or nil if it can't be read.
+func (c *blobCache) get(hash plumbing.Hash) []byte {
+	if data, ok := c.blobs[hash]; ok {
+		return data
+	}
+	var data []byte
+	if blob, err := c.repo.BlobObject(hash); err == nil {
+		if reader, err := blob.Reader(); err == nil {
+			data, _ = io.ReadAll(reader)
+			reader.Close()
+		}
+	}
+	c.blobs[hash] = data
+	return data
+}
@@ -0,0 +1,191 @@
+// diff/rename_test.go
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testRepo is a repository with a worktree in a temporary directory.
+type testRepo struct {
+	t    *testing.T
+	repo *git.Repository
+	root string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("failed to init repository: %v", err)
+	}
+	return &testRepo{t: t, repo: repo, root: root}
+}
+
+// commit writes files, removes the paths mapped to "" and commits the result.
+func (r *testRepo) commit(files map[string]string) *object.Commit {
+	r.t.Helper()
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	for path, content := range files {
+		full := filepath.Join(r.root, path)
+		if content == "" {
+			if _, err := worktree.Remove(path); err != nil {
+				r.t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		if _, err := worktree.Add(path); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1700000000, 0)}
+	hash, err := worktree.Commit("commit", &git.CommitOptions{Author: signature, AllowEmptyCommits: true})
+	if err != nil {
+		r.t.Fatalf("failed to commit: %v", err)
+	}
+	commit, err := r.repo.CommitObject(hash)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return commit
+}
+
+// numberedLines returns n lines of the form "<prefix> line <i>".
+func numberedLines(prefix string, n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%s line %d\n", prefix, i)
+	}
+	return b.String()
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"both empty", "", "", 100},
+		{"identical", "a\nb\nc\n", "a\nb\nc\n", 100},
+		{"one empty", "a\n", "", 0},
+		{"disjoint", "a\nb\n", "c\nd\n", 0},
+		{"one line changed", "aaaa\nbbbb\ncccc\ndddd\n", "aaaa\nbbbb\ncccc\neeee\n", 75},
+		{"line appended", "aaaa\nbbbb\ncccc\n", "aaaa\nbbbb\ncccc\ndddd\n", 75},
+		{"reordered", "aaaa\nbbbb\n", "bbbb\naaaa\n", 100},
+		{"duplicates counted once each", "aaaa\naaaa\n", "aaaa\nbbbb\n", 50},
+		{"missing final newline", "aaaa\nbbbb\n", "aaaa\nbbbb", 50},
+	}
+	for _, tt := range tests {
+		if got := similarity([]byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("%s: similarity() = %d, want %d", tt.name, got, tt.want)
+		}
+		if got := similarity([]byte(tt.b), []byte(tt.a)); got != tt.want {
+			t.Errorf("%s: similarity() reversed = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSizesCompatible(t *testing.T) {
+	tests := []struct {
+		a, b      int64
+		threshold int
+		want      bool
+	}{
+		{0, 0, 50, true},
+		{100, 50, 50, true},
+		{100, 49, 50, false},
+		{10, 100, 90, false},
+		{95, 100, 90, true},
+	}
+	for _, tt := range tests {
+		if got := sizesCompatible(tt.a, tt.b, tt.threshold); got != tt.want {
+			t.Errorf("sizesCompatible(%d, %d, %d) = %v, want %v", tt.a, tt.b, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestDetectRenamesAndCopies(t *testing.T) {
+	r := newTestRepo(t)
+	original := numberedLines("original", 40)
+	// Four of 40 lines changed leave 90% of the content
+	edited := "edited line 1\nedited line 2\nedited line 3\nedited line 4\n" + strings.SplitAfterN(original, "\n", 5)[4]
+	source := numberedLines("source", 30)
+	first := r.commit(map[string]string{
+		"keep.txt":    "unchanged\n",
+		"old/a.txt":   original,
+		"exact.txt":   numberedLines("exact", 20),
+		"src.txt":     source,
+		"gone.txt":    numberedLines("gone", 10),
+		"similar.txt": numberedLines("similar", 20),
+	})
+	second := r.commit(map[string]string{
+		"old/a.txt":   "",
+		"new/a.txt":   edited,
+		"exact.txt":   "",
+		"moved.txt":   numberedLines("exact", 20),
+		"copy1.txt":   source,
+		"copy2.txt":   source,
+		"gone.txt":    "",
+		"unrelated":   numberedLines("unrelated", 10),
+		"similar.txt": "",
+	})
+
+	tests := []struct {
+		name    string
+		opts    Options
+		renames []string // old->new:similarity
+		copies  []string
+	}{
+		{"renames", Options{}, []string{"exact.txt->moved.txt:100", "old/a.txt->new/a.txt:90"}, nil},
+		{"copies", Options{FindCopies: true}, []string{"exact.txt->moved.txt:100", "old/a.txt->new/a.txt:90"}, []string{"src.txt->copy1.txt:100", "src.txt->copy2.txt:100"}},
+		{"strict threshold", Options{RenameThreshold: 95}, []string{"exact.txt->moved.txt:100"}, nil},
+		{"fast mode matches identical files only", Options{Fast: true}, []string{"exact.txt->moved.txt:100"}, nil},
+		{"disabled", Options{NoRenames: true, FindCopies: true}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree1, _ := first.Tree()
+			tree2, _ := second.Tree()
+			changes, err := object.DiffTree(tree1, tree2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			renames, copies, remaining := detectRenames(r.repo, tree1, changes, tt.opts)
+			if got := describeRenames(renames); strings.Join(got, " ") != strings.Join(tt.renames, " ") {
+				t.Errorf("renames = %v, want %v", got, tt.renames)
+			}
+			if got := describeRenames(copies); strings.Join(got, " ") != strings.Join(tt.copies, " ") {
+				t.Errorf("copies = %v, want %v", got, tt.copies)
+			}
+			if want := len(changes) - 2*len(renames) - len(copies); len(remaining) != want {
+				t.Errorf("%d changes remain, want %d", len(remaining), want)
+			}
+		})
+	}
+}
+
+// describeRenames formats renames as old->new:similarity.
+func describeRenames(renames []RenamedFile) []string {
+	var described []string
+	for _, rename := range renames {
+		described = append(described, fmt.Sprintf("%s->%s:%d", rename.OldPath, rename.NewPath, rename.Similarity))
+	}
+	return described
+}
@@ -0,0 +1,104 @@
+// diff/stats.go
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+)
+
+// FileStat describes the size of the change to a single file.
+type FileStat struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"` // source of a rename or copy
+	Change  string `json:"change"`             // "created", "deleted", "modified", "renamed" or "copied"
+	Added   int    `json:"added"`              // lines added, 0 for binary files
+	Removed int    `json:"removed"`            // lines removed, 0 for binary files
+	Binary  bool   `json:"binary,omitempty"`
+	OldSize int64  `json:"old_size"` // blob size in bytes in the first commit, 0 if absent
+	NewSize int64  `json:"new_size"` // blob size in bytes in the second commit, 0 if absent
+}
+
+// ChangeTotals adds up the file statistics of a group of files.
+type ChangeTotals struct {
+	Files   int `json:"files"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// Summary totals the file statistics per change category and per folder.
+type Summary struct {
+	Total      ChangeTotals            `json:"total"`
+	Categories map[string]ChangeTotals `json:"categories"`
+	Folders    map[string]ChangeTotals `json:"folders"`
+}
+
+// fileStatFor counts the lines added and removed by a file patch and looks up
+// the blob sizes on both sides.
+func fileStatFor(repo *git.Repository, filePatch fdiff.FilePatch, change string) FileStat {
+	from, to := filePatch.Files()
+	stat := FileStat{Change: change, Binary: filePatch.IsBinary()}
+	if from != nil {
+		stat.Path = from.Path()
+		stat.OldSize = blobSize(repo, from)
+	}
+	if to != nil {
+		if from != nil && from.Path() != to.Path() {
+			stat.OldPath = from.Path()
+		}
+		stat.Path = to.Path()
+		stat.NewSize = blobSize(repo, to)
+	}
+
+	for _, chunk := range filePatch.Chunks() {
+		switch chunk.Type() {
+		case fdiff.Add:
+			stat.Added += countLines(chunk.Content())
+		case fdiff.Delete:
+			stat.Removed += countLines(chunk.Content())
+		}
+	}
+	return stat
+}
+
+// blobSize returns the size of the blob behind file, or 0 if it can't be read.
+func blobSize(repo *git.Repository, file fdiff.File) int64 {
+	size, err := repo.Storer.EncodedObjectSize(file.Hash())
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// countLines counts the lines of a chunk, including a last line without a newline.
+func countLines(content string) int {
+	lines := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+	return lines
+}
+
+// summarize totals stats per change category and per parent folder.
+func summarize(stats []FileStat) *Summary {
+	summary := &Summary{Categories: make(map[string]ChangeTotals), Folders: make(map[string]ChangeTotals)}
+	for _, stat := range stats {
+		summary.Total = summary.Total.add(stat)
+		summary.Categories[stat.Change] = summary.Categories[stat.Change].add(stat)
+		folder := getParentFolder(stat.Path)
+		summary.Folders[folder] = summary.Folders[folder].add(stat)
+	}
+	return summary
+}
+
+// add returns the totals with stat counted in.
+func (t ChangeTotals) add(stat FileStat) ChangeTotals {
+	return ChangeTotals{Files: t.Files + 1, Added: t.Added + stat.Added, Removed: t.Removed + stat.Removed}
+}
+
+// sortFileStats orders stats by path.
+func sortFileStats(stats []FileStat) {
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Path < stats[j].Path })
+}
@@ -0,0 +1,124 @@
+// diff/submodule.go
+package diff
+
+import (
+	"mygitapp/logger"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// SubmoduleChange describes a change of a submodule pointer between two commits.
+type SubmoduleChange struct {
+	Path      string                   `json:"path"`
+	Change    string                   `json:"change"` // "added", "removed" or "updated"
+	OldCommit string                   `json:"old_commit,omitempty"`
+	NewCommit string                   `json:"new_commit,omitempty"`
+	Diff      *ComparisonResultGrouped `json:"diff,omitempty"` // submodule diff, with --recurse-submodules
+}
+
+// collectSubmoduleChanges picks the gitlink entries out of a tree diff.
+func collectSubmoduleChanges(repo *git.Repository, changes object.Changes, opts Options) []SubmoduleChange {
+	var submoduleChanges []SubmoduleChange
+	for _, change := range changes {
+		fromIsSubmodule := change.From.TreeEntry.Mode == filemode.Submodule
+		toIsSubmodule := change.To.TreeEntry.Mode == filemode.Submodule
+		if !fromIsSubmodule && !toIsSubmodule {
+			continue
+		}
+
+		submoduleChange := SubmoduleChange{}
+		switch {
+		case fromIsSubmodule && toIsSubmodule:
+			submoduleChange.Change = "updated"
+			submoduleChange.Path = change.To.Name
+			submoduleChange.OldCommit = change.From.TreeEntry.Hash.String()
+			submoduleChange.NewCommit = change.To.TreeEntry.Hash.String()
+		case toIsSubmodule:
+			submoduleChange.Change = "added"
+			submoduleChange.Path = change.To.Name
+			submoduleChange.NewCommit = change.To.TreeEntry.Hash.String()
+		default:
+			submoduleChange.Change = "removed"
+			submoduleChange.Path = change.From.Name
+			submoduleChange.OldCommit = change.From.TreeEntry.Hash.String()
+		}
+
+		if opts.RecurseSubmodules && submoduleChange.Change == "updated" {
+			submoduleChange.Diff = diffSubmodule(repo, submoduleChange, opts)
+		}
+		submoduleChanges = append(submoduleChanges, submoduleChange)
+	}
+	return submoduleChanges
+}
+
+// diffSubmodule compares the old and new commits of an updated submodule
+// inside the submodule's own repository. It returns nil if the submodule
+// has not been initialized or the commits are not available.
+func diffSubmodule(repo *git.Repository, change SubmoduleChange, opts Options) *ComparisonResultGrouped {
+	subRepo, err := openSubmodule(repo, change.Path)
+	if err != nil {
+		logger.Log.WithError(err).Warnf("Skipping diff of submodule %s", change.Path)
+		return nil
+	}
+
+	oldCommit, err := subRepo.CommitObject(plumbing.NewHash(change.OldCommit))
+	if err != nil {
+		logger.Log.WithError(err).Warnf("Skipping diff of submodule %s: old commit not found", change.Path)
+		return nil
+	}
+	newCommit, err := subRepo.CommitObject(plumbing.NewHash(change.NewCommit))
+	if err != nil {
+		logger.Log.WithError(err).Warnf("Skipping diff of submodule %s: new commit not found", change.Path)
+		return nil
+	}
+
+	// Path filters apply to the paths of the superproject only
+	opts.Include, opts.Exclude = nil, nil
+	result := compareCommits(subRepo, oldCommit, newCommit, opts)
+	result.CommitDetails[0] = CommitDetails{Hash: oldCommit.Hash.String(), Timestamp: oldCommit.Committer.When}
+	result.CommitDetails[1] = CommitDetails{Hash: newCommit.Hash.String(), Timestamp: newCommit.Committer.When}
+	return &result
+}
+
+// openSubmodule opens the repository of the submodule checked out at path.
+func openSubmodule(repo *git.Repository, path string) (*git.Repository, error) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range submodules {
+		if sub.Config().Path == path {
+			return sub.Repository()
+		}
+	}
+	return nil, git.ErrSubmoduleNotFound
+}
+
+// gitlinkPatch returns the patch of a submodule pointer change, with a
+// "Subproject commit" line per side as in git.
+func gitlinkPatch(change SubmoduleChange) fdiff.FilePatch {
+	var patch treeFilePatch
+	if change.OldCommit != "" {
+		patch.from = gitlinkFile(change.Path, change.OldCommit)
+		patch.chunks = append(patch.chunks, textChunk{content: "Subproject commit " + change.OldCommit + "\n", op: fdiff.Delete})
+	}
+	if change.NewCommit != "" {
+		patch.to = gitlinkFile(change.Path, change.NewCommit)
+		patch.chunks = append(patch.chunks, textChunk{content: "Subproject commit " + change.NewCommit + "\n", op: fdiff.Add})
+	}
+	return patch
+}
+
+// gitlinkFile describes the gitlink entry of a submodule at path.
+func gitlinkFile(path, commit string) fdiff.File {
+	entry := object.ChangeEntry{Name: path, TreeEntry: object.TreeEntry{Name: path, Mode: filemode.Submodule, Hash: plumbing.NewHash(commit)}}
+	return treeFile{entry}
+}
@@ -0,0 +1,80 @@
+// diff/submodule_test.go
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// changeEntry returns a change entry for a tree entry at path, or an empty
+// entry if mode is zero.
+func changeEntry(path string, mode filemode.FileMode, hash string) object.ChangeEntry {
+	if mode == 0 {
+		return object.ChangeEntry{}
+	}
+	return object.ChangeEntry{Name: path, TreeEntry: object.TreeEntry{Name: path, Mode: mode, Hash: plumbing.NewHash(hash)}}
+}
+
+func TestCollectSubmoduleChanges(t *testing.T) {
+	oldCommit := strings.Repeat("1", 40)
+	newCommit := strings.Repeat("2", 40)
+	blob := strings.Repeat("3", 40)
+
+	tests := []struct {
+		name   string
+		change *object.Change
+		want   []SubmoduleChange
+	}{
+		{
+			name:   "added",
+			change: &object.Change{To: changeEntry("lib", filemode.Submodule, newCommit)},
+			want:   []SubmoduleChange{{Path: "lib", Change: "added", NewCommit: newCommit}},
+		},
+		{
+			name:   "removed",
+			change: &object.Change{From: changeEntry("vendor/lib", filemode.Submodule, oldCommit)},
+			want:   []SubmoduleChange{{Path: "vendor/lib", Change: "removed", OldCommit: oldCommit}},
+		},
+		{
+			name:   "updated",
+			change: &object.Change{From: changeEntry("lib", filemode.Submodule, oldCommit), To: changeEntry("lib", filemode.Submodule, newCommit)},
+			want:   []SubmoduleChange{{Path: "lib", Change: "updated", OldCommit: oldCommit, NewCommit: newCommit}},
+		},
+		{
+			name:   "file replaced by a submodule",
+			change: &object.Change{From: changeEntry("lib", filemode.Regular, blob), To: changeEntry("lib", filemode.Submodule, newCommit)},
+			want:   []SubmoduleChange{{Path: "lib", Change: "added", NewCommit: newCommit}},
+		},
+		{
+			name:   "regular file",
+			change: &object.Change{From: changeEntry("a.txt", filemode.Regular, blob), To: changeEntry("a.txt", filemode.Regular, oldCommit)},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collectSubmoduleChanges(nil, object.Changes{tt.change}, Options{})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectSubmoduleChanges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitlinkPatch(t *testing.T) {
+	oldCommit := strings.Repeat("1", 40)
+	newCommit := strings.Repeat("2", 40)
+	change := SubmoduleChange{Path: "lib", Change: "updated", OldCommit: oldCommit, NewCommit: newCommit}
+
+	got := filePatchFor(gitlinkPatch(change), defaultContextLines, newPatchBudget(Options{}))
+	want := FilePatch{Path: "lib", Hunks: []Hunk{{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1,
+		Lines: []string{"-Subproject commit " + oldCommit, "+Subproject commit " + newCommit}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gitlink patch = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+// export/cli.go
+package export
+
+import (
+	"flag"
+	"io"
+	"mygitapp/logger"
+	"os"
+	"strings"
+)
+
+// RunExport runs the export command.
+func RunExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	ref := flags.String("ref", "", "branch, tag, commit SHA or revision expression to export")
+	path := flags.String("path", "", "folder to limit the archive to")
+	format := flags.String("format", "", "archive format: tar.gz or zip")
+	output := flags.String("output", "-", "file to write the archive to, - for stdout")
+	skipLFS := flags.Bool("skip-lfs", false, "archive Git LFS pointer files instead of the objects")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		logger.Log.Error("Invalid arguments for export")
+		logger.Log.Error("Usage: export [--ref revision] [--path folder] [--format tar.gz|zip] [--output file] [--skip-lfs] <git URI>")
+		os.Exit(1)
+	}
+	gitRepoURI := flags.Arg(0)
+
+	// Without an explicit format, pick it from the output file name
+	if *format == "" && strings.HasSuffix(strings.ToLower(*output), ".zip") {
+		*format = string(FormatZip)
+	}
+	archiveFormat, err := ParseFormat(*format)
+	if err != nil {
+		logger.Log.WithError(err).Error("Invalid arguments for export")
+		os.Exit(1)
+	}
+
+	opts := Options{Ref: *ref, Path: *path, Format: archiveFormat, SkipLFS: *skipLFS}
+
+	if *output == "-" {
+		if err := ExportRepository(gitRepoURI, os.Stdout, opts); err != nil {
+			logger.Log.WithError(err).Error("Export operation failed")
+			os.Exit(1)
+		}
+		logger.Log.Info("Export operation completed successfully")
+		return
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to create %s", *output)
+		os.Exit(1)
+	}
+	err = ExportRepository(gitRepoURI, file, opts)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*output)
+		logger.Log.WithError(err).Error("Export operation failed")
+		os.Exit(1)
+	}
+	logger.Log.Infof("Exported archive to %s", *output)
+	logger.Log.Info("Export operation completed successfully")
+}
@@ -0,0 +1,279 @@
+// export/export.go
+package export
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mygitapp/fetch"
+	"mygitapp/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Format is an archive format.
+type Format string
+
+const (
+	FormatTarGz Format = "tar.gz"
+	FormatZip   Format = "zip"
+)
+
+// archiveModTime is the modification time of every archive entry, so that the
+// same tree always produces the same archive. It is the earliest time zip can store.
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Options controls what ExportRepository writes.
+type Options struct {
+	Ref     string // revision to export; overrides any ref embedded in the URI
+	Path    string // folder to limit the archive to; entries are relative to it
+	Format  Format // archive format, FormatTarGz if empty
+	SkipLFS bool   // archive Git LFS pointer files instead of the objects they point to
+}
+
+// ParseFormat parses an archive format name. An empty string means FormatTarGz.
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case "", FormatTarGz, "tgz":
+		return FormatTarGz, nil
+	case FormatZip:
+		return FormatZip, nil
+	}
+	return "", fmt.Errorf("unknown archive format %q (expected tar.gz or zip)", format)
+}
+
+// entry is a single file, symlink or directory of the archive.
+type entry struct {
+	path string
+	mode filemode.FileMode
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+// ExportRepository fetches gitRepoURI and writes the tree of the resolved ref
+// to w as an archive without the .git directory.
+func ExportRepository(gitRepoURI string, w io.Writer, opts Options) error {
+	tempDir, err := os.MkdirTemp("", "git-repo-*")
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to create temporary directory")
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	result, err := fetch.CloneRepository(gitRepoURI, tempDir, fetch.Options{Ref: opts.Ref, Path: opts.Path, SkipLFS: opts.SkipLFS})
+	if err != nil {
+		return err
+	}
+
+	repo, err := git.PlainOpen(tempDir)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to open cloned repository")
+		return fmt.Errorf("failed to open cloned repository: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %v", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to load HEAD commit: %v", err)
+	}
+
+	// Downloaded LFS objects replace their pointers in the archive
+	lfsFiles := make(map[string]string)
+	for _, file := range result.LFSFiles {
+		lfsFiles[file.Path] = filepath.Join(tempDir, filepath.FromSlash(file.Path))
+	}
+
+	entries, err := treeEntries(repo, commit, opts.Path, lfsFiles)
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Infof("Exporting %d entries of %s as %s", len(entries), commit.Hash, opts.Format)
+	switch opts.Format {
+	case FormatZip:
+		return writeZip(w, entries)
+	default:
+		return writeTarGz(w, entries)
+	}
+}
+
+// treeEntries lists the entries of the commit tree below path in sorted order.
+// Files listed in lfsFiles are read from disk instead of the repository.
+func treeEntries(repo *git.Repository, commit *object.Commit, path string, lfsFiles map[string]string) ([]entry, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tree of %s: %v", commit.Hash, err)
+	}
+	prefix := strings.Trim(path, "/")
+	if prefix != "" {
+		tree, err = tree.Tree(prefix)
+		if err != nil {
+			logger.Log.WithError(err).Errorf("Path %s not found", prefix)
+			return nil, fmt.Errorf("path %s not found in %s: %v", prefix, commit.Hash, err)
+		}
+		prefix += "/"
+	}
+
+	var entries []entry
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, treeEntry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk tree of %s: %v", commit.Hash, err)
+		}
+
+		e := entry{path: name, mode: treeEntry.Mode}
+		switch treeEntry.Mode {
+		case filemode.Dir:
+			e.path += "/"
+		case filemode.Submodule:
+			// Submodule contents are not part of this repository
+			continue
+		default:
+			if diskPath, ok := lfsFiles[prefix+name]; ok {
+				info, err := os.Stat(diskPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read LFS object for %s: %v", name, err)
+				}
+				e.size = info.Size()
+				e.open = func() (io.ReadCloser, error) { return os.Open(diskPath) }
+				break
+			}
+			blob, err := repo.BlobObject(treeEntry.Hash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s: %v", name, err)
+			}
+			e.size = blob.Size
+			e.open = blob.Reader
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	return entries, nil
+}
+
+// fileMode maps a git file mode to the permissions stored in the archive.
+func fileMode(mode filemode.FileMode) os.FileMode {
+	switch mode {
+	case filemode.Dir:
+		return os.ModeDir | 0o755
+	case filemode.Executable:
+		return 0o755
+	case filemode.Symlink:
+		return os.ModeSymlink | 0o777
+	}
+	return 0o644
+}
+
+// readEntry reads the full contents of e.
+func readEntry(e entry) ([]byte, error) {
+	reader, err := e.open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// copyEntry streams the contents of e to w.
+func copyEntry(w io.Writer, e entry) error {
+	reader, err := e.open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", e.path, err)
+	}
+	defer reader.Close()
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to write %s: %v", e.path, err)
+	}
+	return nil
+}
+
+// writeTarGz writes entries to w as a gzip-compressed tar archive.
+func writeTarGz(w io.Writer, entries []entry) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:    e.path,
+			Mode:    int64(fileMode(e.mode).Perm()),
+			ModTime: archiveModTime,
+		}
+		switch e.mode {
+		case filemode.Dir:
+			header.Typeflag = tar.TypeDir
+		case filemode.Symlink:
+			target, err := readEntry(e)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %v", e.path, err)
+			}
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = string(target)
+		default:
+			header.Typeflag = tar.TypeReg
+			header.Size = e.size
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %v", e.path, err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if err := copyEntry(tw, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish tar archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish gzip stream: %v", err)
+	}
+	return nil
+}
+
+// writeZip writes entries to w as a zip archive. Symlinks are stored the way
+// Info-ZIP does, as entries with the symlink mode holding the link target.
+func writeZip(w io.Writer, entries []entry) error {
+	zw := zip.NewWriter(w)
+
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.path, Method: zip.Deflate, Modified: archiveModTime}
+		header.SetMode(fileMode(e.mode))
+		if e.mode == filemode.Dir {
+			header.Method = zip.Store
+		}
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", e.path, err)
+		}
+		if e.mode != filemode.Dir {
+			if err := copyEntry(fw, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip archive: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// fetch/cli.go
+package fetch
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mygitapp/logger"
+	"os"
+	"strings"
+)
+
+// RunFetch runs the fetch command.
+func RunFetch(args []string) {
+	flags := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	ref := flags.String("ref", "", "branch, tag, commit SHA, revision expression or version selector to check out")
+	prerelease := flags.Bool("prerelease", false, "let version selectors pick pre-release tags")
+	path := flags.String("path", "", "folder to restrict the checkout to")
+	skipLFS := flags.Bool("skip-lfs", false, "leave Git LFS pointer files in place")
+	submodules := flags.Bool("recurse-submodules", false, "recursively fetch submodules")
+	verify := flags.String("verify", "off", "signature verification policy: off, warn or require")
+	keyring := flags.String("gpg-keyring", "", "path to an armored OpenPGP keyring for GPG signatures")
+	allowedSigners := flags.String("allowed-signers", "", "path to an SSH allowed-signers file for SSH signatures")
+	force := flags.Bool("force", false, "overwrite a non-empty target directory holding something else")
+	releaseAssets := flags.String("release-assets", "", "glob of release assets to download, for release URLs")
+	manifestPath := flags.String("manifest", "", "fetch every repository of a discover manifest")
+
+	// Allow optional targetDir, or only a base directory with a manifest
+	err := flags.Parse(args)
+	if err != nil || (*manifestPath == "" && (flags.NArg() < 1 || flags.NArg() > 2)) || (*manifestPath != "" && flags.NArg() > 1) {
+		logger.Log.Error("Invalid arguments for fetch")
+		logger.Log.Error("Usage: fetch [--ref revision] [--prerelease] [--path folder] [--skip-lfs] [--recurse-submodules] [--verify off|warn|require] [--gpg-keyring file] [--allowed-signers file] [--force] [--release-assets glob] <git URI> [targetDir]")
+		logger.Log.Error("       fetch [options] --manifest file [baseDir]")
+		os.Exit(1)
+	}
+
+	verifyMode, err := ParseVerifyMode(*verify)
+	if err != nil {
+		logger.Log.WithError(err).Error("Invalid arguments for fetch")
+		os.Exit(1)
+	}
+
+	opts := Options{
+		Ref:            *ref,
+		Prerelease:     *prerelease,
+		Path:           *path,
+		SkipLFS:        *skipLFS,
+		Submodules:     *submodules,
+		Verify:         verifyMode,
+		Keyring:        *keyring,
+		AllowedSigners: *allowedSigners,
+		Force:          *force,
+		ReleaseAssets:  *releaseAssets,
+	}
+
+	if *manifestPath != "" {
+		fetchManifest(*manifestPath, flags.Arg(0), opts)
+		return
+	}
+
+	gitRepoURI := flags.Arg(0)
+	targetDir := flags.Arg(1)
+	result, err := CloneRepository(gitRepoURI, targetDir, opts)
+	if err != nil {
+		logger.Log.WithError(err).Error("Fetch operation failed")
+		os.Exit(1)
+	}
+	if result.ResolvedTag != "" {
+		logger.Log.Infof("Fetched release tag %s", result.ResolvedTag)
+	}
+	if result.Signature != nil && result.Signature.Verified {
+		logger.Log.Infof("Fetched %s %s signed by %s", result.Signature.Object, result.Signature.Hash, result.Signature.Signer)
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to marshal result to JSON")
+		os.Exit(1)
+	}
+
+	fmt.Println(string(output))
+
+	logger.Log.Info("Fetch operation completed successfully")
+}
+
+// RunRefs runs the refs command.
+func RunRefs(args []string) {
+	flags := flag.NewFlagSet("refs", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	pattern := flags.String("filter", "", "glob matched against full or short reference names")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		logger.Log.Error("Invalid arguments for refs")
+		logger.Log.Error("Usage: refs [--filter glob] <git URI>")
+		os.Exit(1)
+	}
+
+	refs, err := ListRemoteRefs(flags.Arg(0), *pattern)
+	if err != nil {
+		logger.Log.WithError(err).Error("Refs operation failed")
+		os.Exit(1)
+	}
+	if refs == nil {
+		refs = []RemoteRef{}
+	}
+
+	output, err := json.MarshalIndent(refs, "", "  ")
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to marshal result to JSON")
+		os.Exit(1)
+	}
+
+	fmt.Println(string(output))
+}
+
+// RunMirror runs the mirror command.
+func RunMirror(args []string) {
+	flags := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	refs := flags.String("refs", "", "comma-separated globs of refs to mirror (default refs/heads/*,refs/tags/*)")
+	prune := flags.Bool("prune", false, "delete mirrored refs that no longer exist in the source")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		logger.Log.Error("Invalid arguments for mirror")
+		logger.Log.Error("Usage: mirror [--refs patterns] [--prune] <source URI> <destination URI>")
+		os.Exit(1)
+	}
+
+	opts := MirrorOptions{Prune: *prune}
+	for _, pattern := range strings.Split(*refs, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			opts.Patterns = append(opts.Patterns, pattern)
+		}
+	}
+
+	result, err := MirrorRepository(flags.Arg(0), flags.Arg(1), opts)
+	if err != nil {
+		logger.Log.WithError(err).Error("Mirror operation failed")
+		os.Exit(1)
+	}
+	if result.Pushed == nil {
+		result.Pushed = []MirroredRef{}
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to marshal result to JSON")
+		os.Exit(1)
+	}
+
+	fmt.Println(string(output))
+
+	logger.Log.Info("Mirror operation completed successfully")
+}
+
+// fetchManifest fetches the repositories of a manifest into baseDir and prints their results as JSON.
+// It exits with status 1 if any repository failed.
+func fetchManifest(manifestPath, baseDir string, opts Options) {
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		logger.Log.WithError(err).Error("Fetch operation failed")
+		os.Exit(1)
+	}
+	if baseDir == "" {
+		baseDir = "."
+	}
+
+	results, err := FetchManifest(manifest, baseDir, opts)
+	if results == nil {
+		results = []*Result{}
+	}
+	output, marshalErr := json.MarshalIndent(results, "", "  ")
+	if marshalErr != nil {
+		logger.Log.WithError(marshalErr).Error("Failed to marshal result to JSON")
+		os.Exit(1)
+	}
+
+	fmt.Println(string(output))
+
+	if err != nil {
+		logger.Log.WithError(err).Error("Fetch operation failed for some repositories")
+		os.Exit(1)
+	}
+	logger.Log.Info("Fetch operation completed successfully")
+}
+
+// RunDiscover runs the discover command.
+func RunDiscover(args []string) {
+	flags := flag.NewFlagSet("discover", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	pattern := flags.String("filter", "", "glob matched against repository names")
+	useSSH := flags.Bool("ssh", false, "list SSH clone URLs instead of HTTPS ones")
+	output := flags.String("output", "-", "file to write the manifest to, - for stdout")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		logger.Log.Error("Invalid arguments for discover")
+		logger.Log.Error("Usage: discover [--filter glob] [--ssh] [--output file] <organization, group or project URL>")
+		os.Exit(1)
+	}
+
+	manifest, err := DiscoverRepositories(flags.Arg(0), DiscoverOptions{Pattern: *pattern, SSH: *useSSH})
+	if err != nil {
+		logger.Log.WithError(err).Error("Discover operation failed")
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to marshal result to JSON")
+		os.Exit(1)
+	}
+
+	if *output == "-" {
+		fmt.Println(string(data))
+	} else if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to write manifest to %s", *output)
+		os.Exit(1)
+	}
+
+	logger.Log.Info("Discover operation completed successfully")
+}
@@ -0,0 +1,348 @@
+// fetch/discover.go
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mygitapp/logger"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Manifest lists repositories to fetch, as produced by DiscoverRepositories.
+type Manifest struct {
+	Source       string          `json:"source"`   // organization, group or project the repositories were discovered in
+	Provider     string          `json:"provider"` // github, gitlab or azure-devops
+	Repositories []ManifestEntry `json:"repositories"`
+}
+
+// ManifestEntry is a repository in a Manifest.
+type ManifestEntry struct {
+	Name          string `json:"name"`                     // path relative to the source, used as the target directory
+	URI           string `json:"uri"`                      // URI passed to CloneRepository
+	Ref           string `json:"ref,omitempty"`            // revision to check out, the default branch if empty
+	DefaultBranch string `json:"default_branch,omitempty"` // default branch reported by the provider
+}
+
+// DiscoverOptions controls optional behaviour of DiscoverRepositories.
+type DiscoverOptions struct {
+	Pattern string // glob matched against repository names; everything if empty
+	SSH     bool   // list SSH clone URLs instead of HTTPS ones
+}
+
+// ownerSource is an organization, group or project URL broken down for API calls.
+type ownerSource struct {
+	provider  string
+	owner     string // GitHub organization or user, or GitLab group path
+	apiURL    string // Azure DevOps project URL the _apis path is appended to
+	parsedURL *url.URL
+}
+
+// DiscoverRepositories lists the repositories of a GitHub organization or
+// user, a GitLab group (including subgroups) or an Azure DevOps project
+// through the provider's REST API, following pagination.
+func DiscoverRepositories(ownerURI string, opts DiscoverOptions) (*Manifest, error) {
+	if _, err := path.Match(opts.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid repository pattern %q: %v", opts.Pattern, err)
+	}
+	owner, err := parseOwnerURL(ownerURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ManifestEntry
+	switch owner.provider {
+	case providerGitHub:
+		entries, err = discoverGitHub(owner, opts)
+	case providerGitLab:
+		entries, err = discoverGitLab(owner, opts)
+	case providerAzureDevOps:
+		entries, err = discoverAzureDevOps(owner, opts)
+	}
+	if err != nil {
+		logger.Log.WithError(err).WithField("provider", owner.provider).Error("Failed to discover repositories")
+		return nil, err
+	}
+
+	manifest := &Manifest{Source: ownerURI, Provider: owner.provider, Repositories: []ManifestEntry{}}
+	for _, entry := range entries {
+		if opts.Pattern != "" {
+			if matched, _ := path.Match(opts.Pattern, path.Base(entry.Name)); !matched {
+				continue
+			}
+		}
+		manifest.Repositories = append(manifest.Repositories, entry)
+	}
+	sort.Slice(manifest.Repositories, func(i, j int) bool {
+		return manifest.Repositories[i].Name < manifest.Repositories[j].Name
+	})
+
+	logger.Log.Infof("Discovered %d repositories in %s", len(manifest.Repositories), ownerURI)
+	return manifest, nil
+}
+
+// parseOwnerURL recognises GitHub organization, GitLab group and Azure DevOps project URLs.
+func parseOwnerURL(ownerURI string) (*ownerSource, error) {
+	parsedURL, err := url.Parse(strings.TrimSuffix(ownerURI, "/"))
+	if err != nil || (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") {
+		logger.Log.Error("Invalid organization URL")
+		return nil, fmt.Errorf("invalid organization URL %q: expected an HTTP(S) URL", ownerURI)
+	}
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	owner := &ownerSource{parsedURL: parsedURL}
+
+	switch {
+	case parsedURL.Host == "github.com" && len(segments) == 1 && segments[0] != "":
+		// https://github.com/{organization or user}
+		owner.provider, owner.owner = providerGitHub, segments[0]
+	case isGitLabHost(parsedURL.Hostname()) && segments[0] != "":
+		// https://gitlab.com/{group}/{subgroup...}
+		owner.provider, owner.owner = providerGitLab, strings.Join(segments, "/")
+	case (parsedURL.Host == "dev.azure.com" && len(segments) == 2) || (isAzureDevOpsHost(parsedURL.Hostname()) && segments[0] != ""):
+		// https://dev.azure.com/{organization}/{project}, https://{organization}.visualstudio.com/{project}
+		// or https://{server}/tfs/{collection}/{project}
+		owner.provider = providerAzureDevOps
+		owner.apiURL = fmt.Sprintf("%s://%s/%s", parsedURL.Scheme, parsedURL.Host, strings.Join(segments, "/"))
+	default:
+		logger.Log.Error("Unsupported organization URL structure")
+		return nil, fmt.Errorf("unsupported organization URL %q: expected a GitHub organization, GitLab group or Azure DevOps project", ownerURI)
+	}
+	return owner, nil
+}
+
+// discoverGitHub lists the repositories of a GitHub organization, or of a user
+// if no organization of that name exists.
+func discoverGitHub(owner *ownerSource, opts DiscoverOptions) ([]ManifestEntry, error) {
+	type githubRepo struct {
+		Name          string `json:"name"`
+		CloneURL      string `json:"clone_url"`
+		SSHURL        string `json:"ssh_url"`
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	var repos []githubRepo
+	handle := func(page []byte) error {
+		var pageRepos []githubRepo
+		err := json.Unmarshal(page, &pageRepos)
+		repos = append(repos, pageRepos...)
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/orgs/%s/repos?per_page=100", githubAPIURL(), url.PathEscape(owner.owner))
+	err := getPagedJSON(owner.provider, endpoint, handle)
+	if errors.Is(err, errNotFound) {
+		endpoint = fmt.Sprintf("%s/users/%s/repos?per_page=100", githubAPIURL(), url.PathEscape(owner.owner))
+		err = getPagedJSON(owner.provider, endpoint, handle)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ManifestEntry, 0, len(repos))
+	for _, repo := range repos {
+		entry := ManifestEntry{Name: repo.Name, URI: repo.CloneURL, DefaultBranch: repo.DefaultBranch}
+		if opts.SSH {
+			entry.URI = repo.SSHURL
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// discoverGitLab lists the projects of a GitLab group and its subgroups.
+func discoverGitLab(owner *ownerSource, opts DiscoverOptions) ([]ManifestEntry, error) {
+	type gitlabProject struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		HTTPURL           string `json:"http_url_to_repo"`
+		SSHURL            string `json:"ssh_url_to_repo"`
+		DefaultBranch     string `json:"default_branch"`
+	}
+
+	var projects []gitlabProject
+	endpoint := fmt.Sprintf("%s://%s/api/v4/groups/%s/projects?include_subgroups=true&per_page=100",
+		owner.parsedURL.Scheme, owner.parsedURL.Host, url.PathEscape(owner.owner))
+	err := getPagedJSON(owner.provider, endpoint, func(page []byte) error {
+		var pageProjects []gitlabProject
+		err := json.Unmarshal(page, &pageProjects)
+		projects = append(projects, pageProjects...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ManifestEntry, 0, len(projects))
+	for _, project := range projects {
+		// Subgroup projects keep their path below the group, e.g. team/project
+		name := strings.TrimPrefix(project.PathWithNamespace, owner.owner+"/")
+		entry := ManifestEntry{Name: name, URI: project.HTTPURL, DefaultBranch: project.DefaultBranch}
+		if opts.SSH {
+			entry.URI = project.SSHURL
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// discoverAzureDevOps lists the Git repositories of an Azure DevOps project.
+func discoverAzureDevOps(owner *ownerSource, opts DiscoverOptions) ([]ManifestEntry, error) {
+	type azureRepo struct {
+		Name          string `json:"name"`
+		RemoteURL     string `json:"remoteUrl"`
+		SSHURL        string `json:"sshUrl"`
+		DefaultBranch string `json:"defaultBranch"`
+	}
+
+	var repos []azureRepo
+	endpoint := owner.apiURL + "/_apis/git/repositories?api-version=7.0"
+	err := getPagedJSON(owner.provider, endpoint, func(page []byte) error {
+		var pageRepos struct {
+			Value []azureRepo `json:"value"`
+		}
+		err := json.Unmarshal(page, &pageRepos)
+		repos = append(repos, pageRepos.Value...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ManifestEntry, 0, len(repos))
+	for _, repo := range repos {
+		entry := ManifestEntry{Name: repo.Name, URI: repo.RemoteURL, DefaultBranch: strings.TrimPrefix(repo.DefaultBranch, "refs/heads/")}
+		if opts.SSH {
+			entry.URI = repo.SSHURL
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// errNotFound is returned by getPagedJSON when the endpoint does not exist.
+var errNotFound = errors.New("not found")
+
+// getPagedJSON requests endpoint and every following page, passing each page's
+// body to handle. Pages are followed through the Link header (GitHub, GitLab),
+// GitLab's X-Next-Page header and Azure DevOps continuation tokens. Credentials
+// are only sent to the scheme and host of the first endpoint, since the
+// following page URLs come from the responses.
+func getPagedJSON(provider, endpoint string, handle func([]byte) error) error {
+	first, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid API URL: %v", err)
+	}
+	for endpoint != "" {
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("invalid API URL: %v", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		if req.URL.Scheme == first.Scheme && req.URL.Host == first.Host {
+			setAPIAuth(req, provider)
+		} else {
+			logger.Log.Warnf("Not sending API credentials to %s", req.URL.Host)
+		}
+
+		resp, err := apiClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("API request failed: %v", err)
+		}
+		body, err := readAPIResponse(resp)
+		if err != nil {
+			return err
+		}
+		if err := handle(body); err != nil {
+			return fmt.Errorf("invalid API response from %s: %v", endpoint, err)
+		}
+		logger.Log.Debugf("Fetched %s", endpoint)
+
+		endpoint = nextPageURL(req.URL, resp.Header)
+	}
+	return nil
+}
+
+// readAPIResponse reads the body of a successful API response.
+func readAPIResponse(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("API request for %s failed: %w", resp.Request.URL, errNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request for %s failed: %s", resp.Request.URL, resp.Status)
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid API response from %s: %v", resp.Request.URL, err)
+	}
+	return body, nil
+}
+
+// nextPageURL returns the URL of the page after current, or "" on the last page.
+func nextPageURL(current *url.URL, header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if ok && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+
+	next := *current
+	query := next.Query()
+	switch {
+	case header.Get("X-Next-Page") != "":
+		query.Set("page", header.Get("X-Next-Page"))
+	case header.Get("X-Ms-Continuationtoken") != "":
+		query.Set("continuationToken", header.Get("X-Ms-Continuationtoken"))
+	default:
+		return ""
+	}
+	next.RawQuery = query.Encode()
+	return next.String()
+}
+
+// LoadManifest reads a manifest written by the discover command.
+func LoadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", manifestPath, err)
+	}
+	return &manifest, nil
+}
+
+// FetchManifest fetches every repository of manifest into a directory named
+// after it below baseDir. A failed repository doesn't stop the others; the
+// errors are returned together after all have been attempted.
+func FetchManifest(manifest *Manifest, baseDir string, opts Options) ([]*Result, error) {
+	var results []*Result
+	var errs []error
+	for _, entry := range manifest.Repositories {
+		entryOpts := opts
+		if entry.Ref != "" {
+			entryOpts.Ref = entry.Ref
+		}
+		if !filepath.IsLocal(filepath.FromSlash(entry.Name)) {
+			errs = append(errs, fmt.Errorf("%s: name is not a relative path", entry.Name))
+			continue
+		}
+		targetDir := filepath.Join(baseDir, filepath.FromSlash(entry.Name))
+		logger.Log.Infof("Fetching %s into %s", entry.URI, targetDir)
+
+		result, err := CloneRepository(entry.URI, targetDir, entryOpts)
+		if err != nil {
+			logger.Log.WithError(err).Errorf("Failed to fetch %s", entry.Name)
+			errs = append(errs, fmt.Errorf("%s: %v", entry.Name, err))
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,220 @@
+// fetch/discover_test.go
+package fetch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNextPageURL(t *testing.T) {
+	current, _ := url.Parse("https://api.example.com/groups/acme/projects?per_page=100&page=1")
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"link", http.Header{"Link": {`<https://api.example.com/x?page=2>; rel="next", <https://api.example.com/x?page=5>; rel="last"`}}, "https://api.example.com/x?page=2"},
+		{"link without next", http.Header{"Link": {`<https://api.example.com/x?page=1>; rel="first"`}}, ""},
+		{"next page header", http.Header{"X-Next-Page": {"2"}}, "https://api.example.com/groups/acme/projects?page=2&per_page=100"},
+		{"continuation token", http.Header{"X-Ms-Continuationtoken": {"abc=="}}, "https://api.example.com/groups/acme/projects?continuationToken=abc%3D%3D&page=1&per_page=100"},
+		{"last page", http.Header{"X-Next-Page": {""}}, ""},
+	}
+	for _, tt := range tests {
+		if got := nextPageURL(current, tt.header); got != tt.want {
+			t.Errorf("%s: nextPageURL() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseOwnerURL(t *testing.T) {
+	t.Setenv("GITLAB_HOSTS", "git.example.com")
+	t.Setenv("AZURE_DEVOPS_HOSTS", "tfs.example.com")
+	tests := []struct {
+		uri, provider, owner, apiURL string
+	}{
+		{"https://github.com/acme", providerGitHub, "acme", ""},
+		{"https://gitlab.com/acme/platform/", providerGitLab, "acme/platform", ""},
+		{"http://git.example.com/acme", providerGitLab, "acme", ""},
+		{"https://dev.azure.com/acme/project", providerAzureDevOps, "", "https://dev.azure.com/acme/project"},
+		{"https://tfs.example.com/tfs/coll/project", providerAzureDevOps, "", "https://tfs.example.com/tfs/coll/project"},
+	}
+	for _, tt := range tests {
+		owner, err := parseOwnerURL(tt.uri)
+		if err != nil {
+			t.Errorf("parseOwnerURL(%q) error = %v", tt.uri, err)
+			continue
+		}
+		if owner.provider != tt.provider || owner.owner != tt.owner || owner.apiURL != tt.apiURL {
+			t.Errorf("parseOwnerURL(%q) = %+v", tt.uri, *owner)
+		}
+	}
+
+	for _, uri := range []string{"git@github.com:acme", "https://github.com/acme/repo", "https://dev.azure.com/acme", "https://example.com/acme"} {
+		if _, err := parseOwnerURL(uri); err == nil {
+			t.Errorf("parseOwnerURL(%q) succeeded, want an error", uri)
+		}
+	}
+}
+
+func TestDiscoverGitHub(t *testing.T) {
+	t.Setenv("GIT_USERNAME", "user")
+	t.Setenv("GIT_PASSWORD", "token")
+	recorder := newHeaderRecorder("Authorization")
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /users/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		repo := func(name string) string {
+			return fmt.Sprintf(`{"name":%q,"clone_url":"https://github.com/acme/%[1]s.git","ssh_url":"git@github.com:acme/%[1]s.git","default_branch":"main"}`, name)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprintf(w, "[%s]", repo("api-gateway"))
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/users/acme/repos?per_page=100&page=2>; rel="next"`, server.URL))
+		fmt.Fprintf(w, "[%s,%s]", repo("web"), repo("api-server"))
+	})
+	server = httptest.NewServer(recorder.wrap(mux))
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL)
+
+	manifest, err := DiscoverRepositories("https://github.com/acme", DiscoverOptions{Pattern: "api-*", SSH: true})
+	if err != nil {
+		t.Fatalf("DiscoverRepositories() error = %v", err)
+	}
+	want := []ManifestEntry{
+		{Name: "api-gateway", URI: "git@github.com:acme/api-gateway.git", DefaultBranch: "main"},
+		{Name: "api-server", URI: "git@github.com:acme/api-server.git", DefaultBranch: "main"},
+	}
+	if manifest.Provider != providerGitHub || !reflect.DeepEqual(manifest.Repositories, want) {
+		t.Errorf("DiscoverRepositories() = %+v", manifest)
+	}
+	if got := recorder.get("/users/acme/repos"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want the token", got)
+	}
+}
+
+func TestDiscoverGitLab(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/groups/{group}/projects", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("group") != "acme/platform" || r.URL.Query().Get("include_subgroups") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		project := func(path string) string {
+			return fmt.Sprintf(`{"path_with_namespace":"acme/platform/%s","http_url_to_repo":"https://gitlab.example.com/acme/platform/%[1]s.git","ssh_url_to_repo":"git@gitlab.example.com:acme/platform/%[1]s.git","default_branch":"main"}`, path)
+		}
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprintf(w, "[%s]", project("core"))
+		case "2":
+			w.Header().Set("X-Next-Page", "")
+			fmt.Fprintf(w, "[%s]", project("tools/cli"))
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	t.Setenv("GITLAB_HOSTS", strings.Split(strings.TrimPrefix(server.URL, "http://"), ":")[0])
+
+	manifest, err := DiscoverRepositories(server.URL+"/acme/platform", DiscoverOptions{})
+	if err != nil {
+		t.Fatalf("DiscoverRepositories() error = %v", err)
+	}
+	want := []ManifestEntry{
+		{Name: "core", URI: "https://gitlab.example.com/acme/platform/core.git", DefaultBranch: "main"},
+		{Name: "tools/cli", URI: "https://gitlab.example.com/acme/platform/tools/cli.git", DefaultBranch: "main"},
+	}
+	if manifest.Provider != providerGitLab || !reflect.DeepEqual(manifest.Repositories, want) {
+		t.Errorf("DiscoverRepositories() = %+v", manifest)
+	}
+}
+
+func TestDiscoverAzureDevOps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tfs/coll/project/_apis/git/repositories" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("continuationToken") == "" {
+			w.Header().Set("X-Ms-Continuationtoken", "next")
+			fmt.Fprint(w, `{"value":[{"name":"b","remoteUrl":"https://tfs.example.com/tfs/coll/project/_git/b","defaultBranch":"refs/heads/develop"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value":[{"name":"a","remoteUrl":"https://tfs.example.com/tfs/coll/project/_git/a"}]}`)
+	}))
+	defer server.Close()
+	t.Setenv("AZURE_DEVOPS_HOSTS", strings.Split(strings.TrimPrefix(server.URL, "http://"), ":")[0])
+
+	manifest, err := DiscoverRepositories(server.URL+"/tfs/coll/project", DiscoverOptions{})
+	if err != nil {
+		t.Fatalf("DiscoverRepositories() error = %v", err)
+	}
+	want := []ManifestEntry{
+		{Name: "a", URI: "https://tfs.example.com/tfs/coll/project/_git/a"},
+		{Name: "b", URI: "https://tfs.example.com/tfs/coll/project/_git/b", DefaultBranch: "develop"},
+	}
+	if !reflect.DeepEqual(manifest.Repositories, want) {
+		t.Errorf("DiscoverRepositories() = %+v", manifest.Repositories)
+	}
+}
+
+func TestGetPagedJSONCredentialsStayOnFirstHost(t *testing.T) {
+	t.Setenv("GIT_USERNAME", "user")
+	t.Setenv("GIT_PASSWORD", "token")
+
+	otherRecorder := newHeaderRecorder("PRIVATE-TOKEN")
+	other := httptest.NewServer(otherRecorder.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `["second"]`)
+	})))
+	defer other.Close()
+
+	recorder := newHeaderRecorder("PRIVATE-TOKEN")
+	server := httptest.NewServer(recorder.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/page2>; rel="next"`, other.URL))
+		fmt.Fprint(w, `["first"]`)
+	})))
+	defer server.Close()
+
+	var pages []string
+	err := getPagedJSON(providerGitLab, server.URL+"/page1", func(page []byte) error {
+		pages = append(pages, string(page))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("getPagedJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(pages, []string{`["first"]`, `["second"]`}) {
+		t.Errorf("pages = %v", pages)
+	}
+	if got := recorder.get("/page1"); got != "token" {
+		t.Errorf("PRIVATE-TOKEN on the first host = %q, want the token", got)
+	}
+	if got := otherRecorder.get("/page2"); got != "" {
+		t.Errorf("PRIVATE-TOKEN on another host = %q, want none", got)
+	}
+}
+
+func TestFetchManifestRejectsUnsafeNames(t *testing.T) {
+	manifest := &Manifest{Repositories: []ManifestEntry{
+		{Name: "../escape", URI: "https://example.com/a.git"},
+		{Name: "/abs", URI: "https://example.com/b.git"},
+	}}
+	results, err := FetchManifest(manifest, t.TempDir(), Options{})
+	if len(results) != 0 || err == nil {
+		t.Fatalf("FetchManifest() = %v, %v, want an error for each entry", results, err)
+	}
+	for _, name := range []string{"../escape", "/abs"} {
+		if !strings.Contains(err.Error(), name+": name is not a relative path") {
+			t.Errorf("FetchManifest() error = %v, want one for %s", err, name)
+		}
+	}
+}
@@ -4,7 +4,6 @@ package fetch
 import (
 	"fmt"
 	"mygitapp/logger" // Import the logger package
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,18 +17,62 @@ import (
 	"golang.org/x/crypto/ssh/knownhosts"
 )
 
+// Options controls optional behaviour of CloneRepository.
+type Options struct {
+	Ref            string     // revision or version selector to check out; overrides any ref embedded in the URI
+	Prerelease     bool       // let version selectors such as "latest" pick pre-release tags
+	Path           string     // folder to restrict the checkout to; overrides any path embedded in the URI
+	SkipLFS        bool       // leave Git LFS pointer files in place instead of downloading the objects
+	Submodules     bool       // recursively initialize and check out submodules
+	Verify         VerifyMode // signature verification policy for the checked-out commit or tag
+	Keyring        string     // path to an armored OpenPGP keyring used for GPG signatures
+	AllowedSigners string     // path to an SSH allowed-signers file used for SSH signatures
+	Force          bool       // replace a non-empty target directory that is not a clone of the same repository
+	ReleaseAssets  string     // glob of release assets to download into the target directory, for release URLs
+}
+
+// Result describes the outcome of a fetch.
+type Result struct {
+	URI           string          `json:"uri"`                      // normalized clone URL
+	Provider      string          `json:"provider"`                 // github, gitlab, azure-devops or git
+	RequestedRef  string          `json:"requested_ref,omitempty"`  // ref as given in the URI or options
+	ResolvedRef   string          `json:"resolved_ref,omitempty"`   // full name of the reference checked out, empty for commits
+	RefType       RefType         `json:"ref_type"`                 // kind of reference checked out
+	Commit        string          `json:"commit"`                   // SHA of the checked-out commit
+	CommitTime    time.Time       `json:"commit_time"`              // committer time of the checked-out commit
+	DefaultBranch string          `json:"default_branch,omitempty"` // branch the remote's HEAD points to
+	Path          string          `json:"path,omitempty"`           // folder the checkout is restricted to
+	TargetDir     string          `json:"target_dir"`               // absolute path of the checkout
+	ResolvedTag   string          `json:"resolved_tag,omitempty"`   // tag a version selector resolved to
+	ReleaseAssets []ReleaseAsset  `json:"release_assets,omitempty"`
+	LFSFiles      []LFSFile       `json:"lfs_files,omitempty"`
+	Submodules    []SubmoduleInfo `json:"submodules,omitempty"`
+	Signature     *SignatureInfo  `json:"signature,omitempty"`
+}
+
 // CloneRepository clones a Git repository to the specified directory.
 // If targetDir is empty, it defaults to the repo's name or "cloned-repo-<timestamp>".
-func CloneRepository(gitRepoURI, targetDir string) error {
-	parsedURL, err := url.Parse(gitRepoURI)
+// The fetch happens in a staging directory next to targetDir, which only
+// replaces targetDir once every step has succeeded.
+func CloneRepository(gitRepoURI, targetDir string, opts Options) (*Result, error) {
+	src, err := parseSource(gitRepoURI)
 	if err != nil {
-		logger.Log.WithError(err).Error("Invalid URL")
-		return fmt.Errorf("invalid URL: %v", err)
+		return nil, err
+	}
+	if opts.Ref != "" {
+		src.ref = opts.Ref
+	}
+	if opts.Path != "" {
+		src.path = strings.Trim(opts.Path, "/")
+	}
+	if opts.ReleaseAssets != "" && src.release == "" {
+		logger.Log.Error("Release assets can only be downloaded for release URLs")
+		return nil, fmt.Errorf("release assets require a GitHub or GitLab release URL")
 	}
 
 	// If targetDir is not provided, derive it from the repo name
 	if targetDir == "" {
-		repoName := extractRepoName(parsedURL.Path)
+		repoName := extractRepoName(src.repoPath())
 		if repoName == "" {
 			// Default to "cloned-repo-<date/timestamp>" if the repo name can't be derived
 			targetDir = fmt.Sprintf("cloned-repo-%s", time.Now().Format("20060102-150405"))
@@ -39,314 +82,263 @@ func CloneRepository(gitRepoURI, targetDir string) error {
 		}
 	}
 
-	// Determine the platform based on the host
-	switch parsedURL.Host {
-	case "github.com":
-		pathSegments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
-		if len(pathSegments) >= 3 && (pathSegments[2] == "tree" || pathSegments[2] == "releases") {
-			return handleGitHubSpecificURL(gitRepoURI, targetDir, pathSegments)
-		}
-	case "dev.azure.com", "ssh.dev.azure.com":
-		return handleAzureDevOpsURL(gitRepoURI, targetDir, parsedURL)
-	case "gitlab.com":
-		return handleGitLabURL(gitRepoURI, targetDir, parsedURL)
+	if err := checkTargetDir(targetDir, src.cloneURL, opts.Force); err != nil {
+		logger.Log.WithError(err).Error("Refusing to fetch into target directory")
+		return nil, err
 	}
-
-	// Default cloning behavior for standard Git URIs
-	cloneOptions := &git.CloneOptions{
-		URL: gitRepoURI,
-	}
-
-	if strings.HasPrefix(gitRepoURI, "https://") {
-		cloneOptions.Auth = getHTTPAuth()
-	} else if strings.HasPrefix(gitRepoURI, "git@") {
-		cloneOptions.Auth = getSSHAuth()
-	} else {
-		logger.Log.Error("Unsupported Git repository URI format")
-		return fmt.Errorf("unsupported Git repository URI format")
+	stagingDir, err := newStagingDir(targetDir)
+	if err != nil {
+		return nil, err
 	}
+	// Leave nothing behind unless the fetch completes
+	defer os.RemoveAll(stagingDir)
 
-	_, err = git.PlainClone(targetDir, false, cloneOptions)
+	repo, err := cloneSource(src, stagingDir)
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to clone repository")
-		return err
+		return nil, err
 	}
 
-	logger.Log.Infof("Successfully cloned repository %s to %s", gitRepoURI, targetDir)
-	return nil
-}
-
-// extractRepoName extracts the repository name from the URL path.
-func extractRepoName(urlPath string) string {
-	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
-	if len(segments) >= 2 {
-		return strings.TrimSuffix(segments[len(segments)-1], ".git") // Handles URLs with or without the .git suffix
+	// The fresh clone has the remote's default branch checked out
+	defaultBranch, err := ResolveRevision(repo, string(plumbing.HEAD))
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to resolve default branch")
+		return nil, err
 	}
-	return ""
-}
 
-// handleGitHubSpecificURL handles cloning based on GitHub's tree (branch) or releases (tag) URLs.
-func handleGitHubSpecificURL(gitRepoURI, targetDir string, pathSegments []string) error {
-	// Construct the base Git URL (e.g., https://github.com/user/repo.git)
-	baseURL := fmt.Sprintf("https://github.com/%s/%s.git", pathSegments[0], pathSegments[1])
-
-	// Clone the repository
-	cloneOptions := &git.CloneOptions{
-		URL:  baseURL,
-		Auth: getHTTPAuth(),
+	rev := defaultBranch
+	if src.ref != "" {
+		rev, err = checkoutSourceRef(repo, src, opts.Prerelease)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// If no specific ref, default to default branch
+		logger.Log.Infof("No specific branch, tag or commit specified. Using default branch.")
 	}
 
-	if strings.HasPrefix(gitRepoURI, "git@") {
-		cloneOptions.Auth = getSSHAuth()
+	if src.path != "" {
+		if err := restrictToPath(repo, src.path); err != nil {
+			return nil, err
+		}
 	}
 
-	repo, err := git.PlainClone(targetDir, false, cloneOptions)
+	result, err := newResult(repo, src, rev, defaultBranch, targetDir)
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to clone repository")
-		return fmt.Errorf("failed to clone repository: %v", err)
+		return nil, err
 	}
-
-	// Determine whether we're dealing with a branch or a tag
-	if pathSegments[2] == "tree" && len(pathSegments) > 3 {
-		// Handle branch
-		return checkoutBranch(repo, pathSegments[3])
-	} else if pathSegments[2] == "releases" && len(pathSegments) > 4 && pathSegments[3] == "tag" {
-		// Handle tag
-		return checkoutTag(repo, pathSegments[4])
-	}
-
-	logger.Log.Error("Unsupported GitHub URL structure")
-	return fmt.Errorf("unsupported GitHub URL structure")
-}
-
-// handleAzureDevOpsURL handles cloning based on Azure DevOps URLs.
-func handleAzureDevOpsURL(gitRepoURI, targetDir string, parsedURL *url.URL) error {
-	// Example Azure DevOps HTTPS URL: https://dev.azure.com/{organization}/{project}/_git/{repo}?version=GB{branch} or GT{tag}
-	// Example Azure DevOps SSH URL: git@ssh.dev.azure.com:v3/{organization}/{project}/{repo}
-
-	// Parse query parameters for branch or tag
-	query := parsedURL.Query()
-	version := query.Get("version") // e.g., GBbranch or GTtag
-
-	var branchName, tagName string
-	if strings.HasPrefix(version, "GB") {
-		branchName = strings.TrimPrefix(version, "GB")
-	} else if strings.HasPrefix(version, "GT") {
-		tagName = strings.TrimPrefix(version, "GT")
+	if !opts.SkipLFS {
+		result.LFSFiles, err = resolveLFSPointers(repo, src.cloneURL)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Extract organization, project, repo from path
-	pathSegments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
-	var organization, project, repo string
-
-	if parsedURL.Host == "dev.azure.com" {
-		// HTTPS URL: /{organization}/{project}/_git/{repo}
-		if len(pathSegments) >= 4 && pathSegments[2] == "_git" {
-			organization = pathSegments[0]
-			project = pathSegments[1]
-			repo = pathSegments[3]
-		} else {
-			logger.Log.Error("Invalid Azure DevOps HTTPS URL structure")
-			return fmt.Errorf("invalid Azure DevOps HTTPS URL structure")
-		}
-	} else if parsedURL.Host == "ssh.dev.azure.com" {
-		// SSH URL: v3/{organization}/{project}/{repo}
-		sshPath := strings.TrimPrefix(parsedURL.Path, "/v3/")
-		sshSegments := strings.Split(strings.Trim(sshPath, "/"), "/")
-		if len(sshSegments) >= 3 {
-			organization = sshSegments[0]
-			project = sshSegments[1]
-			repo = sshSegments[2]
-		} else {
-			logger.Log.Error("Invalid Azure DevOps SSH URL structure")
-			return fmt.Errorf("invalid Azure DevOps SSH URL structure")
+	if opts.Submodules {
+		result.Submodules, err = updateSubmodules(repo, src.cloneURL, "", int(git.DefaultSubmoduleRecursionDepth))
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		logger.Log.Error("Unsupported Azure DevOps host")
-		return fmt.Errorf("unsupported Azure DevOps host: %s", parsedURL.Host)
 	}
 
-	// Construct the base Git URL
-	var baseURL string
-	if parsedURL.Host == "dev.azure.com" {
-		baseURL = fmt.Sprintf("https://dev.azure.com/%s/%s/_git/%s", organization, project, repo)
-	} else { // ssh.dev.azure.com
-		baseURL = fmt.Sprintf("git@ssh.dev.azure.com:v3/%s/%s/%s", organization, project, repo)
+	result.Signature, err = verifyCheckout(repo, rev, opts)
+	if err != nil {
+		return result, err
 	}
 
-	// Clone the repository
-	cloneOptions := &git.CloneOptions{
-		URL:  baseURL,
-		Auth: getHTTPAuth(),
+	if opts.ReleaseAssets != "" {
+		result.ReleaseAssets, err = downloadReleaseAssets(src, opts.ReleaseAssets, stagingDir)
+		if err != nil {
+			return result, err
+		}
 	}
 
-	if strings.HasPrefix(gitRepoURI, "git@") {
-		cloneOptions.Auth = getSSHAuth()
+	if err := promoteStagingDir(stagingDir, targetDir); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to move fetched repository into %s", targetDir)
+		return result, err
 	}
+	logger.Log.Infof("Fetched repository into %s", targetDir)
+	return result, nil
+}
 
-	repoObj, err := git.PlainClone(targetDir, false, cloneOptions)
+// newResult describes the checkout of rev from src that will end up in targetDir.
+func newResult(repo *git.Repository, src *source, rev, defaultBranch *Revision, targetDir string) (*Result, error) {
+	commit, err := repo.CommitObject(rev.Hash)
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to clone Azure DevOps repository")
-		return fmt.Errorf("failed to clone Azure DevOps repository: %v", err)
+		return nil, fmt.Errorf("failed to load commit %s: %v", rev.Hash, err)
 	}
-
-	// Checkout the specified branch or tag
-	if branchName != "" {
-		return checkoutBranch(repoObj, branchName)
-	} else if tagName != "" {
-		return checkoutTag(repoObj, tagName)
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target directory %s: %v", targetDir, err)
 	}
 
-	// If no specific branch or tag, default to default branch
-	logger.Log.Infof("No specific branch or tag specified. Using default branch.")
-	return nil
+	result := &Result{
+		URI:          src.cloneURL,
+		Provider:     src.provider,
+		RequestedRef: src.ref,
+		ResolvedRef:  rev.Name.String(),
+		RefType:      rev.Type,
+		Commit:       rev.Hash.String(),
+		CommitTime:   commit.Committer.When,
+		Path:         src.path,
+		TargetDir:    absTarget,
+	}
+	if defaultBranch.Type == RefBranch {
+		result.DefaultBranch = defaultBranch.Name.Short()
+	}
+	if rev.Type == RefTag && IsVersionSelector(rev.Spec) {
+		result.ResolvedTag = rev.Name.Short()
+	}
+	return result, nil
 }
 
-// handleGitLabURL handles cloning based on GitLab URLs.
-func handleGitLabURL(gitRepoURI, targetDir string, parsedURL *url.URL) error {
-	// Example GitLab HTTPS URL: https://gitlab.com/{group}/{project}.git?ref={branch or tag}
-	// Example GitLab SSH URL: git@gitlab.com:{group}/{project}.git
+// cloneSource clones the repository described by src into targetDir.
+func cloneSource(src *source, targetDir string) (*git.Repository, error) {
+	if !IsRemoteURL(src.cloneURL) {
+		logger.Log.Error("Unsupported Git repository URI format")
+		return nil, fmt.Errorf("unsupported Git repository URI format")
+	}
 
-	// Parse query parameters for branch or tag
-	query := parsedURL.Query()
-	ref := query.Get("ref") // e.g., branch or tag name
+	cloneOptions := &git.CloneOptions{
+		URL:  src.cloneURL,
+		Auth: authForURL(src.cloneURL),
+	}
 
-	var branchName, tagName string
-	if ref != "" {
-		// GitLab doesn't differentiate between branch and tag in the ref parameter
-		// We'll attempt to checkout as a branch first, then as a tag
-		branchName = ref
-		tagName = ref
+	repo, err := git.PlainClone(targetDir, false, cloneOptions)
+	if err != nil {
+		logger.Log.WithError(err).WithField("provider", src.provider).Error("Failed to clone repository")
+		return nil, fmt.Errorf("failed to clone repository: %v", err)
 	}
 
-	// Extract group and project from path
-	pathSegments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
-	var group, project string
+	logger.Log.Infof("Successfully cloned repository %s to %s", src.cloneURL, targetDir)
+	return repo, nil
+}
 
-	if parsedURL.Host == "gitlab.com" {
-		if len(pathSegments) >= 2 {
-			group = pathSegments[0]
-			project = strings.TrimSuffix(pathSegments[1], ".git")
-		} else {
-			logger.Log.Error("Invalid GitLab HTTPS URL structure")
-			return fmt.Errorf("invalid GitLab HTTPS URL structure")
+// checkoutSourceRef checks out the ref of src. Refs taken from web URLs such as
+// /tree/{ref}/{path} may carry a trailing file path, so trailing segments are
+// dropped until the remainder resolves.
+func checkoutSourceRef(repo *git.Repository, src *source, prerelease bool) (*Revision, error) {
+	ref := src.ref
+	for src.refPath {
+		if _, err := ResolveRevision(repo, ref); err == nil {
+			break
 		}
-	} else if parsedURL.Host == "ssh.gitlab.com" {
-		// SSH URL: {group}/{project}.git
-		sshPath := strings.TrimPrefix(parsedURL.Path, "/")
-		sshSegments := strings.Split(strings.TrimSuffix(sshPath, ".git"), "/")
-		if len(sshSegments) >= 2 {
-			group = sshSegments[0]
-			project = sshSegments[1]
-		} else {
-			logger.Log.Error("Invalid GitLab SSH URL structure")
-			return fmt.Errorf("invalid GitLab SSH URL structure")
+		i := strings.LastIndex(ref, "/")
+		if i < 0 {
+			ref = src.ref
+			break
 		}
-	} else {
-		logger.Log.Error("Unsupported GitLab host")
-		return fmt.Errorf("unsupported GitLab host: %s", parsedURL.Host)
-	}
-
-	// Construct the base Git URL
-	var baseURL string
-	if parsedURL.Host == "gitlab.com" {
-		baseURL = fmt.Sprintf("https://gitlab.com/%s/%s.git", group, project)
-	} else { // ssh.gitlab.com
-		baseURL = fmt.Sprintf("git@gitlab.com:%s/%s.git", group, project)
+		ref = ref[:i]
 	}
+	return checkoutRevision(repo, ref, prerelease)
+}
 
-	// Clone the repository
-	cloneOptions := &git.CloneOptions{
-		URL:  baseURL,
-		Auth: getHTTPAuth(),
+// restrictToPath turns the checkout into a sparse checkout of a single folder.
+func restrictToPath(repo *git.Repository, path string) error {
+	head, err := repo.Head()
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to resolve HEAD")
+		return fmt.Errorf("failed to resolve HEAD: %v", err)
 	}
-
-	if strings.HasPrefix(gitRepoURI, "git@") {
-		cloneOptions.Auth = getSSHAuth()
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to load HEAD commit: %v", err)
 	}
-
-	repoObj, err := git.PlainClone(targetDir, false, cloneOptions)
+	tree, err := commit.Tree()
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to clone GitLab repository")
-		return fmt.Errorf("failed to clone GitLab repository: %v", err)
+		return fmt.Errorf("failed to load HEAD tree: %v", err)
 	}
-
-	// Checkout the specified branch or tag
-	if branchName != "" {
-		err := checkoutBranch(repoObj, branchName)
-		if err != nil {
-			// Attempt to checkout as a tag if branch checkout fails
-			logger.Log.Warnf("Failed to checkout branch '%s', attempting to checkout as a tag.", branchName)
-			return checkoutTag(repoObj, tagName)
-		}
-		return nil
+	if _, err := tree.Tree(path); err != nil {
+		logger.Log.WithError(err).Errorf("Path %s not found", path)
+		return fmt.Errorf("path %s not found in %s: %v", path, head.Hash(), err)
 	}
 
-	// If no specific ref, default to default branch
-	logger.Log.Infof("No specific branch or tag specified. Using default branch.")
-	return nil
-}
-
-// checkoutBranch checks out the specified branch in the cloned repository.
-func checkoutBranch(repo *git.Repository, branchName string) error {
 	worktree, err := repo.Worktree()
 	if err != nil {
 		logger.Log.WithError(err).Error("Failed to get worktree")
 		return fmt.Errorf("failed to get worktree: %v", err)
 	}
 
-	err = worktree.Checkout(&git.CheckoutOptions{
-		Branch: plumbing.NewBranchReferenceName(branchName),
-	})
-	if err != nil {
-		logger.Log.WithError(err).Errorf("Failed to checkout branch %s", branchName)
-		return fmt.Errorf("failed to checkout branch %s: %v", branchName, err)
+	// Checkout defaults to master without a branch or hash, so keep HEAD as it is
+	checkoutOptions := &git.CheckoutOptions{SparseCheckoutDirectories: []string{path}, Force: true}
+	if head.Name().IsBranch() {
+		checkoutOptions.Branch = head.Name()
+	} else {
+		checkoutOptions.Hash = head.Hash()
+	}
+	if err := worktree.Checkout(checkoutOptions); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to restrict checkout to %s", path)
+		return fmt.Errorf("failed to restrict checkout to %s: %v", path, err)
 	}
-	logger.Log.Infof("Checked out branch: %s", branchName)
+	logger.Log.Infof("Restricted checkout to %s", path)
 	return nil
 }
 
-// checkoutTag checks out the specified tag in the cloned repository.
-func checkoutTag(repo *git.Repository, tagName string) error {
-	tagRef, err := repo.Tag(tagName)
-	if err != nil {
-		logger.Log.WithError(err).Errorf("Failed to find tag %s", tagName)
-		return fmt.Errorf("failed to find tag %s: %v", tagName, err)
+// extractRepoName extracts the repository name from the URL path.
+func extractRepoName(urlPath string) string {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	if len(segments) >= 2 {
+		return strings.TrimSuffix(segments[len(segments)-1], ".git") // Handles URLs with or without the .git suffix
 	}
+	return ""
+}
 
-	worktree, err := repo.Worktree()
-	if err != nil {
-		logger.Log.WithError(err).Error("Failed to get worktree")
-		return fmt.Errorf("failed to get worktree: %v", err)
-	}
+// isHTTPURL reports whether gitURL is an HTTP(S) URL.
+func isHTTPURL(gitURL string) bool {
+	return strings.HasPrefix(gitURL, "https://") || strings.HasPrefix(gitURL, "http://")
+}
 
-	err = worktree.Checkout(&git.CheckoutOptions{
-		Hash: tagRef.Hash(),
-	})
-	if err != nil {
-		logger.Log.WithError(err).Errorf("Failed to checkout tag %s", tagName)
-		return fmt.Errorf("failed to checkout tag %s: %v", tagName, err)
+// isSSHURL reports whether gitURL is an ssh:// or scp-like (git@host:path) URL.
+func isSSHURL(gitURL string) bool {
+	return strings.HasPrefix(gitURL, "ssh://") || (!strings.Contains(gitURL, "://") && scpLikeURI.MatchString(gitURL))
+}
+
+// IsRemoteURL reports whether gitURL is an HTTP(S) or SSH URL rather than a
+// local path.
+func IsRemoteURL(gitURL string) bool {
+	return isHTTPURL(gitURL) || isSSHURL(gitURL)
+}
+
+// authForURL picks the authentication method for gitURL: HTTP credentials for
+// HTTPS URLs, SSH keys for SSH URLs and none otherwise. Credentials are never
+// sent over plain HTTP.
+func authForURL(gitURL string) transport.AuthMethod {
+	switch {
+	case strings.HasPrefix(gitURL, "https://"):
+		if auth := getHTTPAuth(); auth != nil {
+			return auth
+		}
+	case isSSHURL(gitURL):
+		return getSSHAuth()
 	}
-	logger.Log.Infof("Checked out tag: %s", tagName)
 	return nil
 }
 
 // getSSHAuth handles SSH authentication for git@ URIs.
 func getSSHAuth() transport.AuthMethod {
-	sshAuth, err := gitssh.NewPublicKeysFromFile("git", filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa"), "")
+	sshAuth, err := sshKeyAuth(filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa"))
 	if err != nil {
 		logger.Log.WithError(err).Fatal("Failed to create SSH auth method")
 	}
+	return sshAuth
+}
+
+// sshKeyAuth authenticates with the private key in keyFile, checking host keys
+// against ~/.ssh/known_hosts.
+func sshKeyAuth(keyFile string) (transport.AuthMethod, error) {
+	sshAuth, err := gitssh.NewPublicKeysFromFile("git", keyFile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SSH key %s: %v", keyFile, err)
+	}
 
 	// Load known_hosts file to avoid host key verification failure
 	knownHostsFile := filepath.Join(os.Getenv("HOME"), ".ssh", "known_hosts")
 	hostKeyCallback, err := knownhosts.New(knownHostsFile)
 	if err != nil {
-		logger.Log.WithError(err).Fatal("Failed to load known_hosts file")
+		return nil, fmt.Errorf("failed to load known_hosts file: %v", err)
 	}
 
 	sshAuth.HostKeyCallback = hostKeyCallback
-	return sshAuth
+	return sshAuth, nil
 }
 
 // getHTTPAuth handles HTTP authentication (for private HTTPS repos).
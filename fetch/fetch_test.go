@@ -0,0 +1,23 @@
+// fetch/fetch_test.go
+package fetch
+
+import "testing"
+
+func TestIsRemoteURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://github.com/org/repo.git":                  true,
+		"http://git.example.com/repo.git":                  true,
+		"ssh://git@git.example.com:2222/group/project.git": true,
+		"git@github.com:org/repo.git":                      true,
+		"github.com:org/repo.git":                          true,
+		"/srv/git/repo.git":                                false,
+		"./repo":                                           false,
+		"repo":                                             false,
+		"file:///srv/git/repo.git":                         false,
+	}
+	for uri, want := range tests {
+		if got := IsRemoteURL(uri); got != want {
+			t.Errorf("IsRemoteURL(%q) = %v, want %v", uri, got, want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+// fetch/lfs.go
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mygitapp/logger"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	formatcfg "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+	lfsMediaType      = "application/vnd.git-lfs+json"
+)
+
+// LFSMaxPointerSize bounds the size of LFS pointer files: larger blobs are
+// never pointers and need not be read to rule them out.
+const LFSMaxPointerSize = 1024
+
+// LFSPointer is a parsed Git LFS pointer file.
+type LFSPointer struct {
+	OID  string `json:"oid"`  // SHA-256 of the object contents
+	Size int64  `json:"size"` // size of the object in bytes
+}
+
+// LFSFile describes a working tree file whose LFS pointer was replaced by the real object.
+type LFSFile struct {
+	Path string `json:"path"`
+	LFSPointer
+}
+
+// lfsBatchRequest is the request body of the LFS batch API.
+type lfsBatchRequest struct {
+	Operation string       `json:"operation"`
+	Transfers []string     `json:"transfers"`
+	Objects   []LFSPointer `json:"objects"`
+}
+
+// lfsBatchResponse is the response body of the LFS batch API.
+type lfsBatchResponse struct {
+	Objects []struct {
+		LFSPointer
+		Actions map[string]struct {
+			Href   string            `json:"href"`
+			Header map[string]string `json:"header"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// ParseLFSPointer parses data as a Git LFS pointer file. It reports false if
+// data is not a pointer.
+func ParseLFSPointer(data []byte) (*LFSPointer, bool) {
+	if len(data) >= LFSMaxPointerSize || !bytes.HasPrefix(data, []byte(lfsPointerVersion+"\n")) {
+		return nil, false
+	}
+
+	pointer := &LFSPointer{Size: -1}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			pointer.OID = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			pointer.Size = size
+		}
+	}
+	if len(pointer.OID) != sha256.Size*2 || pointer.Size < 0 {
+		return nil, false
+	}
+	return pointer, true
+}
+
+// BlobLFSPointer parses blob as an LFS pointer, without reading blobs too big to be one.
+func BlobLFSPointer(blob *object.Blob) (*LFSPointer, bool) {
+	if blob.Size >= LFSMaxPointerSize {
+		return nil, false
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false
+	}
+	return ParseLFSPointer(data)
+}
+
+// resolveLFSPointers replaces the LFS pointer files checked out in repo with
+// the objects they point to, downloaded through the LFS batch API of cloneURL.
+// Files left out of a sparse checkout are skipped.
+func resolveLFSPointers(repo *git.Repository, cloneURL string) ([]LFSFile, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %v", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD commit: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get worktree")
+		return nil, fmt.Errorf("failed to get worktree: %v", err)
+	}
+	root := worktree.Filesystem.Root()
+
+	// Collect the pointer files present in the working tree
+	var files []LFSFile
+	fileIter, err := commit.Files()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %v", err)
+	}
+	err = fileIter.ForEach(func(f *object.File) error {
+		if _, statErr := os.Lstat(filepath.Join(root, f.Name)); statErr != nil {
+			return nil
+		}
+		if pointer, ok := BlobLFSPointer(&f.Blob); ok {
+			files = append(files, LFSFile{Path: f.Name, LFSPointer: *pointer})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for LFS pointers: %v", err)
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	endpoint := lfsEndpoint(root, cloneURL)
+	logger.Log.Infof("Downloading %d LFS objects from %s", len(files), endpoint)
+	withAuth := lfsAuthAllowed(endpoint, cloneURL)
+	if !withAuth {
+		logger.Log.Warnf("Not sending clone credentials to LFS endpoint %s", endpoint)
+	}
+
+	actions, err := lfsBatch(endpoint, files, withAuth)
+	if err != nil {
+		logger.Log.WithError(err).Error("LFS batch request failed")
+		return nil, err
+	}
+
+	for _, file := range files {
+		action, ok := actions[file.OID]
+		if !ok {
+			return nil, fmt.Errorf("LFS server returned no download for %s (%s)", file.Path, file.OID)
+		}
+		if err := downloadLFSObject(action, file, filepath.Join(root, file.Path), endpoint, withAuth); err != nil {
+			logger.Log.WithError(err).Errorf("Failed to download LFS object for %s", file.Path)
+			return nil, err
+		}
+		logger.Log.Debugf("Downloaded LFS object for %s", file.Path)
+	}
+	return files, nil
+}
+
+// lfsEndpoint returns the LFS server URL: lfs.url from .lfsconfig if set,
+// otherwise <repository URL>.git/info/lfs over HTTPS.
+func lfsEndpoint(root, cloneURL string) string {
+	if data, err := os.ReadFile(filepath.Join(root, ".lfsconfig")); err == nil {
+		cfg := formatcfg.New()
+		if err := formatcfg.NewDecoder(bytes.NewReader(data)).Decode(cfg); err == nil {
+			if lfsURL := cfg.Section("lfs").Options.Get("url"); lfsURL != "" {
+				return strings.TrimSuffix(lfsURL, "/")
+			}
+		}
+	}
+
+	repoURL := cloneURL
+	if isSSHURL(cloneURL) {
+		// SSH remotes serve LFS over HTTPS on the same host
+		if parsedURL, err := parseGitURI(cloneURL); err == nil {
+			repoURL = fmt.Sprintf("https://%s%s", parsedURL.Hostname(), parsedURL.Path)
+		}
+	}
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	if !strings.HasSuffix(repoURL, ".git") {
+		repoURL += ".git"
+	}
+	return repoURL + "/info/lfs"
+}
+
+// lfsAuthAllowed reports whether clone credentials may be sent to endpoint:
+// only over HTTPS and only to the host of the clone URL, since the endpoint
+// may come from the .lfsconfig of the repository being fetched.
+func lfsAuthAllowed(endpoint, cloneURL string) bool {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme != "https" {
+		return false
+	}
+	cloneParsed, err := parseGitURI(cloneURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(endpointURL.Hostname(), cloneParsed.Hostname())
+}
+
+// lfsDownloadAction is where and how to download a single LFS object.
+type lfsDownloadAction struct {
+	href   string
+	header map[string]string
+}
+
+// lfsBatch asks the LFS server for download actions for files, keyed by OID.
+// Clone credentials are sent along only if withAuth is set.
+func lfsBatch(endpoint string, files []LFSFile, withAuth bool) (map[string]lfsDownloadAction, error) {
+	request := lfsBatchRequest{Operation: "download", Transfers: []string{"basic"}}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if !seen[file.OID] {
+			seen[file.OID] = true
+			request.Objects = append(request.Objects, file.LFSPointer)
+		}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LFS endpoint: %v", err)
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	if withAuth {
+		setLFSAuth(req)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("LFS batch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LFS batch request failed: %s", resp.Status)
+	}
+
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("invalid LFS batch response: %v", err)
+	}
+
+	actions := make(map[string]lfsDownloadAction)
+	for _, obj := range batch.Objects {
+		if obj.Error != nil {
+			return nil, fmt.Errorf("LFS object %s: %s (%d)", obj.OID, obj.Error.Message, obj.Error.Code)
+		}
+		if download, ok := obj.Actions["download"]; ok {
+			actions[obj.OID] = lfsDownloadAction{href: download.Href, header: download.Header}
+		}
+	}
+	return actions, nil
+}
+
+// downloadLFSObject downloads an LFS object to path, verifying its size and
+// SHA-256 before replacing the pointer file. Clone credentials are only sent
+// along if withAuth is set and the download is served from the LFS endpoint's
+// own host over HTTPS.
+func downloadLFSObject(action lfsDownloadAction, file LFSFile, path, endpoint string, withAuth bool) error {
+	req, err := http.NewRequest(http.MethodGet, action.href, nil)
+	if err != nil {
+		return fmt.Errorf("invalid LFS download URL for %s: %v", file.Path, err)
+	}
+	for key, value := range action.header {
+		req.Header.Set(key, value)
+	}
+	if endpointURL, err := url.Parse(endpoint); withAuth && err == nil && req.URL.Scheme == "https" && endpointURL.Host == req.URL.Host && req.Header.Get("Authorization") == "" {
+		setLFSAuth(req)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download LFS object for %s: %v", file.Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download LFS object for %s: %s", file.Path, resp.Status)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".lfs-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download LFS object for %s: %v", file.Path, err)
+	}
+	if size != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.OID {
+		return fmt.Errorf("LFS object for %s does not match its pointer", file.Path)
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// setLFSAuth adds the HTTP credentials used for cloning to an LFS request.
+func setLFSAuth(req *http.Request) {
+	if auth := getHTTPAuth(); auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
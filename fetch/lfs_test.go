@@ -0,0 +1,283 @@
+// fetch/lfs_test.go
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lfsPointerFor returns the pointer file of content.
+func lfsPointerFor(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerVersion, hex.EncodeToString(sum[:]), len(content))
+}
+
+// lfsServer is a minimal LFS server serving objects by OID.
+type lfsServer struct {
+	*httptest.Server
+	objects map[string]string // contents by OID
+
+	mu         sync.Mutex
+	authorized []string // paths of requests that carried an Authorization header
+}
+
+// newLFSServer starts an LFS server for contents, over TLS if tls is set.
+func newLFSServer(t *testing.T, tls bool, contents ...string) *lfsServer {
+	t.Helper()
+	s := &lfsServer{objects: make(map[string]string)}
+	for _, content := range contents {
+		sum := sha256.Sum256([]byte(content))
+		s.objects[hex.EncodeToString(sum[:])] = content
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		var request lfsBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Operation != "download" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var objects []map[string]any
+		for _, obj := range request.Objects {
+			if _, ok := s.objects[obj.OID]; !ok {
+				objects = append(objects, map[string]any{"oid": obj.OID, "size": obj.Size, "error": map[string]any{"code": 404, "message": "Object does not exist"}})
+				continue
+			}
+			href := s.URL + "/download/" + obj.OID
+			objects = append(objects, map[string]any{"oid": obj.OID, "size": obj.Size, "actions": map[string]any{"download": map[string]any{"href": href}}})
+		}
+		w.Header().Set("Content-Type", lfsMediaType)
+		json.NewEncoder(w).Encode(map[string]any{"objects": objects})
+	})
+	mux.HandleFunc("GET /download/{oid}", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := s.objects[r.PathValue("oid")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			s.mu.Lock()
+			s.authorized = append(s.authorized, r.URL.Path)
+			s.mu.Unlock()
+		}
+		mux.ServeHTTP(w, r)
+	})
+
+	if tls {
+		s.Server = httptest.NewTLSServer(handler)
+	} else {
+		s.Server = httptest.NewServer(handler)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// authorizedPaths returns the paths of the requests that carried credentials.
+func (s *lfsServer) authorizedPaths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.authorized...)
+}
+
+// useTestClient makes http.DefaultClient trust the certificate of server.
+func useTestClient(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	original := http.DefaultClient
+	http.DefaultClient = server.Client()
+	t.Cleanup(func() { http.DefaultClient = original })
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	oid := strings.Repeat("ab", 32)
+	tests := []struct {
+		name string
+		data string
+		want *LFSPointer
+	}{
+		{"pointer", lfsPointerVersion + "\noid sha256:" + oid + "\nsize 12\n", &LFSPointer{OID: oid, Size: 12}},
+		{"extension keys", lfsPointerVersion + "\next-0-foo sha256:" + strings.Repeat("cd", 32) + "\noid sha256:" + oid + "\nsize 0\n", &LFSPointer{OID: oid, Size: 0}},
+		{"plain text", "hello\n", nil},
+		{"missing oid", lfsPointerVersion + "\nsize 12\n", nil},
+		{"short oid", lfsPointerVersion + "\noid sha256:abcd\nsize 12\n", nil},
+		{"missing size", lfsPointerVersion + "\noid sha256:" + oid + "\n", nil},
+		{"bad size", lfsPointerVersion + "\noid sha256:" + oid + "\nsize twelve\n", nil},
+		{"too large", lfsPointerVersion + "\noid sha256:" + oid + "\nsize 12\n" + strings.Repeat("x", LFSMaxPointerSize), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLFSPointer([]byte(tt.data))
+			if ok != (tt.want != nil) {
+				t.Fatalf("ParseLFSPointer() ok = %v, want %v", ok, tt.want != nil)
+			}
+			if ok && *got != *tt.want {
+				t.Errorf("ParseLFSPointer() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestLFSAuthAllowed(t *testing.T) {
+	tests := []struct {
+		endpoint, cloneURL string
+		want               bool
+	}{
+		{"https://git.example.com/org/repo.git/info/lfs", "https://git.example.com/org/repo.git", true},
+		{"https://GIT.example.com/org/repo.git/info/lfs", "https://git.example.com/org/repo.git", true},
+		{"https://git.example.com/org/repo.git/info/lfs", "git@git.example.com:org/repo.git", true},
+		{"http://git.example.com/org/repo.git/info/lfs", "https://git.example.com/org/repo.git", false},
+		{"https://lfs.attacker.example/info/lfs", "https://git.example.com/org/repo.git", false},
+		{"https://git.example.com.attacker.example/info/lfs", "https://git.example.com/org/repo.git", false},
+		{"://bad", "https://git.example.com/org/repo.git", false},
+	}
+	for _, tt := range tests {
+		if got := lfsAuthAllowed(tt.endpoint, tt.cloneURL); got != tt.want {
+			t.Errorf("lfsAuthAllowed(%q, %q) = %v, want %v", tt.endpoint, tt.cloneURL, got, tt.want)
+		}
+	}
+}
+
+func TestLFSEndpoint(t *testing.T) {
+	tests := []struct {
+		cloneURL, want string
+	}{
+		{"https://git.example.com/org/repo.git", "https://git.example.com/org/repo.git/info/lfs"},
+		{"https://git.example.com/org/repo/", "https://git.example.com/org/repo.git/info/lfs"},
+		{"git@git.example.com:org/repo.git", "https://git.example.com/org/repo.git/info/lfs"},
+		{"ssh://git@git.example.com:2222/org/repo", "https://git.example.com/org/repo.git/info/lfs"},
+	}
+	root := t.TempDir()
+	for _, tt := range tests {
+		if got := lfsEndpoint(root, tt.cloneURL); got != tt.want {
+			t.Errorf("lfsEndpoint(%q) = %q, want %q", tt.cloneURL, got, tt.want)
+		}
+	}
+
+	config := "[lfs]\n\turl = https://lfs.example.com/org/repo/\n"
+	if err := os.WriteFile(filepath.Join(root, ".lfsconfig"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := lfsEndpoint(root, tests[0].cloneURL); got != "https://lfs.example.com/org/repo" {
+		t.Errorf("lfsEndpoint() with .lfsconfig = %q", got)
+	}
+}
+
+func TestResolveLFSPointers(t *testing.T) {
+	t.Setenv("GIT_USERNAME", "user")
+	t.Setenv("GIT_PASSWORD", "secret")
+	content := "large binary content\n"
+	server := newLFSServer(t, false, content)
+
+	repo := newTestRepo(t)
+	commitFile(t, repo, "assets/big.bin", lfsPointerFor(content))
+	commitFile(t, repo, "README.md", "readme\n")
+	commitFile(t, repo, ".lfsconfig", "[lfs]\n\turl = "+server.URL+"\n")
+
+	files, err := resolveLFSPointers(repo, "https://git.example.com/org/repo.git")
+	if err != nil {
+		t.Fatalf("resolveLFSPointers() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "assets/big.bin" || files[0].Size != int64(len(content)) {
+		t.Fatalf("resolveLFSPointers() = %+v", files)
+	}
+
+	worktree, _ := repo.Worktree()
+	data, err := os.ReadFile(filepath.Join(worktree.Filesystem.Root(), "assets/big.bin"))
+	if err != nil || string(data) != content {
+		t.Errorf("big.bin = %q, %v, want the LFS object", data, err)
+	}
+	// The endpoint comes from .lfsconfig on another host and over plain HTTP
+	if paths := server.authorizedPaths(); len(paths) != 0 {
+		t.Errorf("credentials sent to %v, want none", paths)
+	}
+}
+
+func TestResolveLFSPointersMismatch(t *testing.T) {
+	content := "expected content\n"
+	server := newLFSServer(t, false)
+	pointer := lfsPointerFor(content)
+	sum := sha256.Sum256([]byte(content))
+	server.objects[hex.EncodeToString(sum[:])] = "tampered content\n"
+
+	repo := newTestRepo(t)
+	commitFile(t, repo, "big.bin", pointer)
+	commitFile(t, repo, ".lfsconfig", "[lfs]\n\turl = "+server.URL+"\n")
+
+	if _, err := resolveLFSPointers(repo, "https://git.example.com/org/repo.git"); err == nil || !strings.Contains(err.Error(), "does not match its pointer") {
+		t.Fatalf("resolveLFSPointers() error = %v, want a mismatch", err)
+	}
+	worktree, _ := repo.Worktree()
+	data, _ := os.ReadFile(filepath.Join(worktree.Filesystem.Root(), "big.bin"))
+	if string(data) != pointer {
+		t.Errorf("big.bin = %q, want the pointer left in place", data)
+	}
+}
+
+func TestLFSBatchObjectError(t *testing.T) {
+	server := newLFSServer(t, false)
+	file := LFSFile{Path: "missing.bin", LFSPointer: LFSPointer{OID: strings.Repeat("0", 64), Size: 1}}
+	if _, err := lfsBatch(server.URL, []LFSFile{file}, false); err == nil || !strings.Contains(err.Error(), "Object does not exist") {
+		t.Errorf("lfsBatch() error = %v, want the object error", err)
+	}
+}
+
+func TestLFSCredentials(t *testing.T) {
+	t.Setenv("GIT_USERNAME", "user")
+	t.Setenv("GIT_PASSWORD", "secret")
+	content := "object\n"
+	endpoint := newLFSServer(t, true, content)
+	other := newLFSServer(t, true, content)
+	useTestClient(t, endpoint.Server)
+
+	sum := sha256.Sum256([]byte(content))
+	file := LFSFile{Path: "obj.bin", LFSPointer: LFSPointer{OID: hex.EncodeToString(sum[:]), Size: int64(len(content))}}
+	path := filepath.Join(t.TempDir(), "obj.bin")
+
+	tests := []struct {
+		name      string
+		withAuth  bool
+		href      string
+		wantBatch bool
+		wantGet   bool
+	}{
+		{"same host", true, endpoint.URL + "/download/" + file.OID, true, true},
+		{"download on another host", true, other.URL + "/download/" + file.OID, true, false},
+		{"credentials not allowed", false, endpoint.URL + "/download/" + file.OID, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint.authorized, other.authorized = nil, nil
+			if err := os.WriteFile(path, []byte(lfsPointerFor(content)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := lfsBatch(endpoint.URL, []LFSFile{file}, tt.withAuth); err != nil {
+				t.Fatalf("lfsBatch() error = %v", err)
+			}
+			if err := downloadLFSObject(lfsDownloadAction{href: tt.href}, file, path, endpoint.URL, tt.withAuth); err != nil {
+				t.Fatalf("downloadLFSObject() error = %v", err)
+			}
+
+			authorized := append(endpoint.authorizedPaths(), other.authorizedPaths()...)
+			gotBatch, gotGet := false, false
+			for _, p := range authorized {
+				gotBatch = gotBatch || p == "/objects/batch"
+				gotGet = gotGet || strings.HasPrefix(p, "/download/")
+			}
+			if gotBatch != tt.wantBatch || gotGet != tt.wantGet {
+				t.Errorf("credentials sent to %v, want batch %v, download %v", authorized, tt.wantBatch, tt.wantGet)
+			}
+		})
+	}
+}
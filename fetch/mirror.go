@@ -0,0 +1,225 @@
+// fetch/mirror.go
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"mygitapp/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// mirrorRemote is the name of the destination remote in the scratch repository.
+const mirrorRemote = "mirror"
+
+// defaultMirrorPatterns are the refs mirrored when no patterns are given.
+var defaultMirrorPatterns = []string{"refs/heads/*", "refs/tags/*"}
+
+// MirrorOptions controls optional behaviour of MirrorRepository.
+type MirrorOptions struct {
+	Patterns []string // globs matched against full or short ref names; branches and tags if empty
+	Prune    bool     // delete destination refs matching Patterns that no longer exist in the source
+}
+
+// MirroredRef is a reference written to the mirror.
+type MirroredRef struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+// MirrorResult describes the outcome of a mirror.
+type MirrorResult struct {
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Pushed      []MirroredRef `json:"pushed"`
+	Pruned      []string      `json:"pruned,omitempty"`
+}
+
+// MirrorRepository copies the refs of the repository sourceURI points to into
+// the repository at destURI. The source goes through the same provider
+// handling and authentication as CloneRepository; the destination is a plain
+// Git URL authenticated with the GIT_MIRROR_* credentials. Either end may
+// also be a local repository path or file:// URL.
+func MirrorRepository(sourceURI, destURI string, opts MirrorOptions) (*MirrorResult, error) {
+	sourceURL, err := mirrorSourceURL(sourceURI)
+	if err != nil {
+		return nil, err
+	}
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = defaultMirrorPatterns
+	}
+	destAuth, err := mirrorAuthForURL(destURI)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceRefs, err := listMirrorRefs(sourceURL, authForURL(sourceURL), patterns)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to list source references")
+		return nil, fmt.Errorf("failed to list source references: %v", err)
+	}
+	if len(sourceRefs) == 0 {
+		return nil, fmt.Errorf("no source references match %s", strings.Join(patterns, ", "))
+	}
+	destRefs, err := listMirrorRefs(destURI, destAuth, patterns)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to list destination references")
+		return nil, fmt.Errorf("failed to list destination references: %v", err)
+	}
+
+	// Fetch the selected refs into a scratch bare repository
+	tempDir, err := os.MkdirTemp("", "git-mirror-*")
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to create temporary directory")
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	repo, err := git.PlainInit(tempDir, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mirror repository: %v", err)
+	}
+
+	result := &MirrorResult{Source: sourceURL, Destination: destURI}
+	var fetchSpecs, pushSpecs []config.RefSpec
+	for name, hash := range sourceRefs {
+		spec := config.RefSpec(fmt.Sprintf("+%s:%s", name, name))
+		fetchSpecs = append(fetchSpecs, spec)
+		if destHash, ok := destRefs[name]; !ok || destHash != hash {
+			pushSpecs = append(pushSpecs, spec)
+			result.Pushed = append(result.Pushed, MirroredRef{Name: name.String(), Hash: hash.String()})
+		}
+	}
+	if opts.Prune {
+		for name := range destRefs {
+			if _, ok := sourceRefs[name]; !ok {
+				pushSpecs = append(pushSpecs, config.RefSpec(":"+name.String()))
+				result.Pruned = append(result.Pruned, name.String())
+			}
+		}
+	}
+	sort.Slice(result.Pushed, func(i, j int) bool { return result.Pushed[i].Name < result.Pushed[j].Name })
+	sort.Strings(result.Pruned)
+
+	if len(pushSpecs) == 0 {
+		logger.Log.Infof("Mirror %s is up to date", destURI)
+		return result, nil
+	}
+
+	source, err := repo.CreateRemote(&config.RemoteConfig{Name: defaultRemote, URLs: []string{sourceURL}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure source remote: %v", err)
+	}
+	err = source.Fetch(&git.FetchOptions{RefSpecs: fetchSpecs, Auth: authForURL(sourceURL), Tags: git.NoTags})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		logger.Log.WithError(err).Error("Failed to fetch source references")
+		return nil, fmt.Errorf("failed to fetch source references: %v", err)
+	}
+	logger.Log.Infof("Fetched %d references from %s", len(fetchSpecs), sourceURL)
+
+	dest, err := repo.CreateRemote(&config.RemoteConfig{Name: mirrorRemote, URLs: []string{destURI}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure destination remote: %v", err)
+	}
+	err = dest.Push(&git.PushOptions{RemoteName: mirrorRemote, RefSpecs: pushSpecs, Auth: destAuth, Force: true})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		logger.Log.WithError(err).Error("Failed to push to destination")
+		return nil, fmt.Errorf("failed to push to destination: %v", err)
+	}
+
+	logger.Log.Infof("Mirrored %d references to %s, pruned %d", len(result.Pushed), destURI, len(result.Pruned))
+	return result, nil
+}
+
+// mirrorSourceURL returns the URL to fetch sourceURI from: the normalized
+// clone URL for remote URIs, or the path itself for local repositories.
+func mirrorSourceURL(sourceURI string) (string, error) {
+	if isLocalRepository(sourceURI) {
+		return sourceURI, nil
+	}
+	src, err := parseSource(sourceURI)
+	if err != nil {
+		return "", err
+	}
+	if !IsRemoteURL(src.cloneURL) {
+		logger.Log.Error("Unsupported Git repository URI format")
+		return "", fmt.Errorf("unsupported Git repository URI format")
+	}
+	return src.cloneURL, nil
+}
+
+// isLocalRepository reports whether uri is a file:// URL or an existing local path.
+func isLocalRepository(uri string) bool {
+	if strings.HasPrefix(uri, "file://") {
+		return true
+	}
+	if IsRemoteURL(uri) {
+		return false
+	}
+	_, err := os.Stat(uri)
+	return err == nil
+}
+
+// listMirrorRefs lists the refs of the repository at url that match patterns.
+// An empty repository has no refs.
+func listMirrorRefs(url string, auth transport.AuthMethod, patterns []string) (map[plumbing.ReferenceName]plumbing.Hash, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: defaultRemote, URLs: []string{url}})
+	advertised, err := remote.List(&git.ListOptions{Auth: auth})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return map[plumbing.ReferenceName]plumbing.Hash{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[plumbing.ReferenceName]plumbing.Hash)
+	for _, ref := range advertised {
+		if ref.Type() != plumbing.HashReference || ref.Name() == plumbing.HEAD {
+			continue
+		}
+		for _, pattern := range patterns {
+			if matchesRefPattern(ref.Name(), pattern) {
+				refs[ref.Name()] = ref.Hash()
+				break
+			}
+		}
+	}
+	return refs, nil
+}
+
+// mirrorAuthForURL picks the authentication method for the mirror destination:
+// GIT_MIRROR_USERNAME and GIT_MIRROR_PASSWORD for HTTPS URLs, and the SSH key
+// in GIT_MIRROR_SSH_KEY (default ~/.ssh/id_rsa) for SSH URLs.
+func mirrorAuthForURL(destURL string) (transport.AuthMethod, error) {
+	switch {
+	case strings.HasPrefix(destURL, "https://"):
+		username := os.Getenv("GIT_MIRROR_USERNAME")
+		password := os.Getenv("GIT_MIRROR_PASSWORD")
+		if username == "" || password == "" {
+			return nil, nil
+		}
+		return &http.BasicAuth{Username: username, Password: password}, nil
+	case isSSHURL(destURL):
+		keyFile := os.Getenv("GIT_MIRROR_SSH_KEY")
+		if keyFile == "" {
+			keyFile = filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa")
+		}
+		auth, err := sshKeyAuth(keyFile)
+		if err != nil {
+			logger.Log.WithError(err).Error("Failed to create SSH auth method for mirror destination")
+			return nil, err
+		}
+		return auth, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,150 @@
+// fetch/mirror_test.go
+package fetch
+
+import (
+	"reflect"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// newBareRepo initializes an empty bare repository and returns its path.
+func newBareRepo(t *testing.T) (*git.Repository, string) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, true)
+	if err != nil {
+		t.Fatalf("failed to init bare repository: %v", err)
+	}
+	return repo, dir
+}
+
+// refHashes returns the branches and tags of repo by full name.
+func refHashes(t *testing.T, repo *git.Repository) map[string]plumbing.Hash {
+	t.Helper()
+	refs, err := repo.References()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := make(map[string]plumbing.Hash)
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && (ref.Name().IsBranch() || ref.Name().IsTag()) {
+			hashes[ref.Name().String()] = ref.Hash()
+		}
+		return nil
+	})
+	return hashes
+}
+
+func TestMirrorRepository(t *testing.T) {
+	source := newTestRepo(t)
+	first := commitFile(t, source, "a.txt", "one\n")
+	second := commitFile(t, source, "a.txt", "two\n")
+	setRef(t, source, "refs/heads/dev", first)
+	setRef(t, source, "refs/heads/feature/x", second)
+	setRef(t, source, "refs/tags/v1.0.0", first)
+	setRef(t, source, "refs/tags/team/v1", second)
+	setRef(t, source, "refs/pull/1/head", second)
+	worktree, _ := source.Worktree()
+	sourcePath := worktree.Filesystem.Root()
+
+	dest, destPath := newBareRepo(t)
+
+	result, err := MirrorRepository(sourcePath, destPath, MirrorOptions{})
+	if err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+	want := map[string]plumbing.Hash{
+		"refs/heads/master":    second,
+		"refs/heads/dev":       first,
+		"refs/heads/feature/x": second,
+		"refs/tags/v1.0.0":     first,
+		"refs/tags/team/v1":    second,
+	}
+	if got := refHashes(t, dest); !reflect.DeepEqual(got, want) {
+		t.Errorf("destination refs = %v, want %v", got, want)
+	}
+	if len(result.Pushed) != 5 || result.Pushed[0].Name != "refs/heads/dev" {
+		t.Errorf("Pushed = %+v", result.Pushed)
+	}
+	if _, err := dest.Reference("refs/pull/1/head", false); err == nil {
+		t.Errorf("refs/pull/1/head was mirrored, want only branches and tags")
+	}
+
+	// A second run finds the mirror up to date
+	result, err = MirrorRepository(sourcePath, destPath, MirrorOptions{})
+	if err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+	if len(result.Pushed) != 0 || len(result.Pruned) != 0 {
+		t.Errorf("second mirror = %+v, want no changes", result)
+	}
+}
+
+func TestMirrorRepositoryPrune(t *testing.T) {
+	source := newTestRepo(t)
+	first := commitFile(t, source, "a.txt", "one\n")
+	second := commitFile(t, source, "a.txt", "two\n")
+	setRef(t, source, "refs/heads/feature/x", first)
+	setRef(t, source, "refs/tags/v1.0.0", first)
+	worktree, _ := source.Worktree()
+	sourcePath := worktree.Filesystem.Root()
+
+	dest, destPath := newBareRepo(t)
+	if _, err := MirrorRepository(sourcePath, "file://"+destPath, MirrorOptions{}); err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+
+	// Move master back, delete the feature branch and the tag
+	setRef(t, source, "refs/heads/master", first)
+	for _, name := range []string{"refs/heads/feature/x", "refs/tags/v1.0.0"} {
+		if err := source.Storer.RemoveReference(plumbing.ReferenceName(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without pruning, deleted refs are kept
+	result, err := MirrorRepository(sourcePath, destPath, MirrorOptions{})
+	if err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+	if len(result.Pruned) != 0 || len(refHashes(t, dest)) != 3 {
+		t.Errorf("mirror without prune = %+v, refs %v", result, refHashes(t, dest))
+	}
+	if got := refHashes(t, dest)["refs/heads/master"]; got != first {
+		t.Errorf("master = %s, want the force-pushed %s (was %s)", got, first, second)
+	}
+
+	// Pruning only touches refs matching the patterns
+	result, err = MirrorRepository(sourcePath, destPath, MirrorOptions{Patterns: []string{"refs/heads/*"}, Prune: true})
+	if err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Pruned, []string{"refs/heads/feature/x"}) {
+		t.Errorf("Pruned = %v, want refs/heads/feature/x", result.Pruned)
+	}
+	want := map[string]plumbing.Hash{"refs/heads/master": first, "refs/tags/v1.0.0": first}
+	if got := refHashes(t, dest); !reflect.DeepEqual(got, want) {
+		t.Errorf("destination refs = %v, want %v", got, want)
+	}
+
+	result, err = MirrorRepository(sourcePath, destPath, MirrorOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("MirrorRepository() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Pruned, []string{"refs/tags/v1.0.0"}) {
+		t.Errorf("Pruned = %v, want refs/tags/v1.0.0", result.Pruned)
+	}
+}
+
+func TestMirrorRepositoryNoMatchingRefs(t *testing.T) {
+	source := newTestRepo(t)
+	commitFile(t, source, "a.txt", "one\n")
+	worktree, _ := source.Worktree()
+	_, destPath := newBareRepo(t)
+
+	if _, err := MirrorRepository(worktree.Filesystem.Root(), destPath, MirrorOptions{Patterns: []string{"release/*"}}); err == nil {
+		t.Errorf("MirrorRepository() succeeded, want an error for patterns matching nothing")
+	}
+}
@@ -0,0 +1,119 @@
+// fetch/refs.go
+package fetch
+
+import (
+	"fmt"
+	"mygitapp/logger"
+	"path"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// peeledSuffix marks the peeled entries servers advertise for annotated tags.
+const peeledSuffix = "^{}"
+
+// RemoteRef is a reference advertised by a remote repository.
+type RemoteRef struct {
+	Name   string  `json:"name"`             // full reference name, e.g. refs/heads/main
+	Type   RefType `json:"type"`             // branch, tag, symbolic or ref
+	Target string  `json:"target"`           // SHA the reference points to
+	Peeled string  `json:"peeled,omitempty"` // commit SHA an annotated tag points to
+	SymRef string  `json:"symref,omitempty"` // reference a symbolic reference such as HEAD points to
+}
+
+// ListRemoteRefs lists the references of the repository gitRepoURI points to
+// without cloning it, like "git ls-remote". Any ref or path embedded in a web
+// URL is ignored. If pattern is not empty, only references whose full or
+// short name matches the glob are returned.
+func ListRemoteRefs(gitRepoURI, pattern string) ([]RemoteRef, error) {
+	src, err := parseSource(gitRepoURI)
+	if err != nil {
+		return nil, err
+	}
+	if !IsRemoteURL(src.cloneURL) {
+		logger.Log.Error("Unsupported Git repository URI format")
+		return nil, fmt.Errorf("unsupported Git repository URI format")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid ref pattern %q: %v", pattern, err)
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: defaultRemote, URLs: []string{src.cloneURL}})
+	advertised, err := remote.List(&git.ListOptions{Auth: authForURL(src.cloneURL), PeelingOption: git.AppendPeeled})
+	if err != nil {
+		logger.Log.WithError(err).WithField("provider", src.provider).Error("Failed to list remote references")
+		return nil, fmt.Errorf("failed to list remote references: %v", err)
+	}
+
+	hashes := make(map[plumbing.ReferenceName]plumbing.Hash)
+	peeled := make(map[string]string)
+	for _, ref := range advertised {
+		name := ref.Name().String()
+		if strings.HasSuffix(name, peeledSuffix) {
+			peeled[strings.TrimSuffix(name, peeledSuffix)] = ref.Hash().String()
+		} else if ref.Type() == plumbing.HashReference {
+			hashes[ref.Name()] = ref.Hash()
+		}
+	}
+
+	var refs []RemoteRef
+	for _, ref := range advertised {
+		name := ref.Name().String()
+		if strings.HasSuffix(name, peeledSuffix) || !matchesRefPattern(ref.Name(), pattern) {
+			continue
+		}
+
+		remoteRef := RemoteRef{Name: name, Target: ref.Hash().String(), Peeled: peeled[name]}
+		switch {
+		case ref.Type() == plumbing.SymbolicReference:
+			remoteRef.Type = RefSymbolic
+			remoteRef.SymRef = ref.Target().String()
+			remoteRef.Target = hashes[ref.Target()].String()
+		case ref.Name().IsBranch():
+			remoteRef.Type = RefBranch
+		case ref.Name().IsTag():
+			remoteRef.Type = RefTag
+		default:
+			remoteRef.Type = RefOther
+		}
+		refs = append(refs, remoteRef)
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	logger.Log.Infof("Listed %d references of %s", len(refs), src.cloneURL)
+	return refs, nil
+}
+
+// matchesRefPattern reports whether the full or short name of name matches
+// the glob pattern. As in git refspecs, a trailing "/*" matches any number of
+// path components, so refs/heads/* also matches refs/heads/feature/x. An
+// empty pattern matches everything.
+func matchesRefPattern(name plumbing.ReferenceName, pattern string) bool {
+	if pattern == "" {
+		return true
+	}
+	prefix, recursive := strings.CutSuffix(pattern, "/*")
+	for _, candidate := range []string{name.String(), name.Short()} {
+		if matched, _ := path.Match(pattern, candidate); matched {
+			return true
+		}
+		if !recursive {
+			continue
+		}
+		// Try every split of candidate into a directory matching the prefix and a non-empty rest
+		for i := 1; i < len(candidate)-1; i++ {
+			if candidate[i] != '/' {
+				continue
+			}
+			if matched, _ := path.Match(prefix, candidate[:i]); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
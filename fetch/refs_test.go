@@ -0,0 +1,34 @@
+// fetch/refs_test.go
+package fetch
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestMatchesRefPattern(t *testing.T) {
+	tests := []struct {
+		name, pattern string
+		want          bool
+	}{
+		{"refs/heads/main", "", true},
+		{"refs/heads/main", "refs/heads/*", true},
+		{"refs/heads/feature/x", "refs/heads/*", true},
+		{"refs/heads/feature/x/y", "refs/heads/*", true},
+		{"refs/tags/team/v1", "refs/tags/*", true},
+		{"refs/tags/v1.0.0", "refs/heads/*", false},
+		{"refs/heads/release/1.0", "release/*", true},
+		{"refs/heads/release", "release/*", false},
+		{"refs/heads/feature/x", "refs/*/feature/*", true},
+		{"refs/tags/v1.2.0", "v1.*", true},
+		{"refs/heads/feature/x", "feature", false},
+		{"refs/heads/feature/x", "refs/heads/feature/x", true},
+		{"refs/pull/1/head", "refs/heads/*", false},
+	}
+	for _, tt := range tests {
+		if got := matchesRefPattern(plumbing.ReferenceName(tt.name), tt.pattern); got != tt.want {
+			t.Errorf("matchesRefPattern(%q, %q) = %v, want %v", tt.name, tt.pattern, got, tt.want)
+		}
+	}
+}
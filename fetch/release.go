@@ -0,0 +1,264 @@
+// fetch/release.go
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mygitapp/logger"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultGitHubAPIURL is the GitHub REST API base URL, overridden by GITHUB_API_URL.
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// apiClient sends provider REST API requests. Go itself only drops the
+// Authorization header on redirects to another host, so the client also drops
+// GitLab's PRIVATE-TOKEN header.
+var apiClient = &http.Client{CheckRedirect: dropAPIAuthOnRedirect}
+
+// ReleaseAsset describes a release asset downloaded into the target directory.
+type ReleaseAsset struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// releaseLink is an asset listed by a provider's release API.
+type releaseLink struct {
+	name   string
+	url    string
+	accept string // Accept header the download needs, if any
+	digest string // "sha256:<hex>" published by the provider, if any
+}
+
+// downloadReleaseAssets downloads the assets of the release src points to
+// whose names match pattern into dir, recording their SHA-256 checksums.
+func downloadReleaseAssets(src *source, pattern, dir string) ([]ReleaseAsset, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid release asset pattern %q: %v", pattern, err)
+	}
+
+	var links []releaseLink
+	var err error
+	switch src.provider {
+	case providerGitHub:
+		links, err = listGitHubReleaseAssets(src)
+	case providerGitLab:
+		links, err = listGitLabReleaseAssets(src)
+	default:
+		err = fmt.Errorf("release assets are not supported for %s repositories", src.provider)
+	}
+	if err != nil {
+		logger.Log.WithError(err).WithField("provider", src.provider).Error("Failed to list release assets")
+		return nil, err
+	}
+
+	var assets []ReleaseAsset
+	for _, link := range links {
+		if matched, _ := path.Match(pattern, link.name); !matched {
+			continue
+		}
+		asset, err := downloadReleaseAsset(src, link, dir)
+		if err != nil {
+			logger.Log.WithError(err).Errorf("Failed to download release asset %s", link.name)
+			return nil, err
+		}
+		logger.Log.Infof("Downloaded release asset %s (%d bytes)", asset.Name, asset.Size)
+		assets = append(assets, *asset)
+	}
+	if len(assets) == 0 {
+		logger.Log.Warnf("No assets of release %s match %s", src.release, pattern)
+	}
+	return assets, nil
+}
+
+// listGitHubReleaseAssets lists the assets of a GitHub release through the REST API.
+func listGitHubReleaseAssets(src *source) ([]releaseLink, error) {
+	var release struct {
+		Assets []struct {
+			Name   string `json:"name"`
+			URL    string `json:"url"`
+			Digest string `json:"digest"`
+		} `json:"assets"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/releases/tags/%s", githubAPIURL(), src.project, url.PathEscape(src.release))
+	if err := getReleaseJSON(src, endpoint, &release); err != nil {
+		return nil, err
+	}
+
+	var links []releaseLink
+	for _, asset := range release.Assets {
+		links = append(links, releaseLink{name: asset.Name, url: asset.URL, accept: "application/octet-stream", digest: asset.Digest})
+	}
+	return links, nil
+}
+
+// listGitLabReleaseAssets lists the asset links of a GitLab release through the REST API.
+func listGitLabReleaseAssets(src *source) ([]releaseLink, error) {
+	scheme := "https"
+	if src.parsedURL.Scheme == "http" {
+		scheme = "http"
+	}
+	var release struct {
+		Assets struct {
+			Links []struct {
+				Name           string `json:"name"`
+				URL            string `json:"url"`
+				DirectAssetURL string `json:"direct_asset_url"`
+			} `json:"links"`
+		} `json:"assets"`
+	}
+	endpoint := fmt.Sprintf("%s://%s/api/v4/projects/%s/releases/%s", scheme, src.parsedURL.Host, url.PathEscape(src.project), url.PathEscape(src.release))
+	if err := getReleaseJSON(src, endpoint, &release); err != nil {
+		return nil, err
+	}
+
+	var links []releaseLink
+	for _, link := range release.Assets.Links {
+		assetURL := link.DirectAssetURL
+		if assetURL == "" {
+			assetURL = link.URL
+		}
+		links = append(links, releaseLink{name: link.Name, url: assetURL})
+	}
+	return links, nil
+}
+
+// getReleaseJSON fetches a release API endpoint and decodes its JSON response into v.
+func getReleaseJSON(src *source, endpoint string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("invalid release API URL: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	setAPIAuth(req, src.provider)
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("release API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("release API request for %s failed: %s", src.release, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid release API response: %v", err)
+	}
+	return nil
+}
+
+// downloadReleaseAsset downloads a single asset into dir. API credentials are
+// only sent along when the asset is served from the repository's own host or
+// the API host; redirects to other hosts drop them.
+func downloadReleaseAsset(src *source, link releaseLink, dir string) (*ReleaseAsset, error) {
+	name := filepath.Base(link.name)
+	if name != link.name || name == "." || name == ".." || name == ".git" {
+		return nil, fmt.Errorf("refusing to write release asset with unsafe name %q", link.name)
+	}
+	target := filepath.Join(dir, name)
+	if _, err := os.Lstat(target); err == nil {
+		return nil, fmt.Errorf("release asset %s would overwrite a file in the checkout", name)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, link.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download URL for release asset %s: %v", name, err)
+	}
+	if link.accept != "" {
+		req.Header.Set("Accept", link.accept)
+	}
+	if isReleaseAPIHost(src, req.URL.Host) {
+		setAPIAuth(req, src.provider)
+	}
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download release asset %s: %v", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download release asset %s: %s", name, resp.Status)
+	}
+
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return nil, fmt.Errorf("failed to download release asset %s: %v", name, err)
+	}
+
+	asset := &ReleaseAsset{Name: name, URL: link.url, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}
+	if digest, ok := strings.CutPrefix(link.digest, "sha256:"); ok && !strings.EqualFold(digest, asset.SHA256) {
+		os.Remove(target)
+		return nil, fmt.Errorf("release asset %s does not match its published checksum", name)
+	}
+	return asset, nil
+}
+
+// githubAPIURL returns the GitHub REST API base URL.
+func githubAPIURL() string {
+	if apiURL := strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/"); apiURL != "" {
+		return apiURL
+	}
+	return defaultGitHubAPIURL
+}
+
+// isReleaseAPIHost reports whether host serves the release API of src, and
+// so may receive its credentials.
+func isReleaseAPIHost(src *source, host string) bool {
+	if src.provider == providerGitHub {
+		apiURL, err := url.Parse(githubAPIURL())
+		return err == nil && apiURL.Host == host
+	}
+	return src.parsedURL.Host == host
+}
+
+// dropAPIAuthOnRedirect removes the API credentials from a redirect that
+// leaves the scheme and host of the original request.
+func dropAPIAuthOnRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != via[0].URL.Scheme || req.URL.Host != via[0].URL.Host {
+		req.Header.Del("PRIVATE-TOKEN")
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+// setAPIAuth adds the HTTP credentials to a provider REST API request the way
+// the provider expects them: the password as a token for GitHub and GitLab,
+// and basic auth with a personal access token for Azure DevOps.
+func setAPIAuth(req *http.Request, provider string) {
+	auth := getHTTPAuth()
+	if auth == nil {
+		return
+	}
+	switch provider {
+	case providerGitLab:
+		req.Header.Set("PRIVATE-TOKEN", auth.Password)
+	case providerAzureDevOps:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	default:
+		req.Header.Set("Authorization", "Bearer "+auth.Password)
+	}
+}
@@ -0,0 +1,232 @@
+// fetch/release_test.go
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// headerRecorder records a request header by request path.
+type headerRecorder struct {
+	header string
+	mu     sync.Mutex
+	seen   map[string]string
+}
+
+func newHeaderRecorder(header string) *headerRecorder {
+	return &headerRecorder{header: header, seen: make(map[string]string)}
+}
+
+// wrap records the header of each request before passing it to handler.
+func (h *headerRecorder) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.Lock()
+		h.seen[r.URL.Path] = r.Header.Get(h.header)
+		h.mu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// get returns the header value sent with the request for path.
+func (h *headerRecorder) get(path string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seen[path]
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadGitHubReleaseAssets(t *testing.T) {
+	t.Setenv("GIT_USERNAME", "user")
+	t.Setenv("GIT_PASSWORD", "token")
+	recorder := newHeaderRecorder("Authorization")
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/org/repo/releases/tags/v1.0", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"assets": []map[string]string{
+			{"name": "app-linux.tar.gz", "url": server.URL + "/assets/1", "digest": "sha256:" + sha256Hex("linux build")},
+			{"name": "app-darwin.tar.gz", "url": server.URL + "/assets/2"},
+			{"name": "checksums.txt", "url": server.URL + "/assets/3"},
+		}})
+	})
+	mux.HandleFunc("GET /assets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/octet-stream" {
+			http.Error(w, "wrong accept header", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(map[string]string{"1": "linux build", "2": "darwin build", "3": "sums"}[r.PathValue("id")]))
+	})
+	server = httptest.NewServer(recorder.wrap(mux))
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL+"/")
+
+	src := &source{provider: providerGitHub, project: "org/repo", release: "v1.0"}
+	dir := t.TempDir()
+	assets, err := downloadReleaseAssets(src, "app-*.tar.gz", dir)
+	if err != nil {
+		t.Fatalf("downloadReleaseAssets() error = %v", err)
+	}
+	if len(assets) != 2 || assets[0].Name != "app-linux.tar.gz" || assets[1].Name != "app-darwin.tar.gz" {
+		t.Fatalf("downloadReleaseAssets() = %+v", assets)
+	}
+	if assets[0].SHA256 != sha256Hex("linux build") || assets[0].Size != int64(len("linux build")) {
+		t.Errorf("asset = %+v", assets[0])
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "app-darwin.tar.gz")); string(data) != "darwin build" {
+		t.Errorf("app-darwin.tar.gz = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checksums.txt")); err == nil {
+		t.Errorf("checksums.txt was downloaded, want only matching assets")
+	}
+	for _, path := range []string{"/repos/org/repo/releases/tags/v1.0", "/assets/1"} {
+		if got := recorder.get(path); got != "Bearer token" {
+			t.Errorf("Authorization for %s = %q, want the token", path, got)
+		}
+	}
+
+	// Existing files are never overwritten
+	if _, err := downloadReleaseAssets(src, "app-linux.tar.gz", dir); err == nil || !strings.Contains(err.Error(), "would overwrite") {
+		t.Errorf("downloadReleaseAssets() error = %v, want an overwrite error", err)
+	}
+}
+
+func TestDownloadReleaseAssetChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	defer server.Close()
+
+	src := &source{provider: providerGitHub, project: "org/repo", release: "v1.0"}
+	dir := t.TempDir()
+	link := releaseLink{name: "app.tar.gz", url: server.URL + "/app.tar.gz", digest: "sha256:" + sha256Hex("original")}
+	if _, err := downloadReleaseAsset(src, link, dir); err == nil || !strings.Contains(err.Error(), "published checksum") {
+		t.Fatalf("downloadReleaseAsset() error = %v, want a checksum mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.tar.gz")); err == nil {
+		t.Errorf("app.tar.gz was kept after a checksum mismatch")
+	}
+}
+
+func TestDownloadReleaseAssetUnsafeName(t *testing.T) {
+	src := &source{provider: providerGitHub}
+	for _, name := range []string{"../evil", "dir/file", ".git", ".."} {
+		if _, err := downloadReleaseAsset(src, releaseLink{name: name, url: "http://127.0.0.1/"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "unsafe name") {
+			t.Errorf("downloadReleaseAsset(%q) error = %v, want an unsafe name error", name, err)
+		}
+	}
+}
+
+func TestDownloadGitLabReleaseAssets(t *testing.T) {
+	t.Setenv("GIT_USERNAME", "user")
+	t.Setenv("GIT_PASSWORD", "token")
+
+	// Assets hosted elsewhere, directly or through a redirect, get no token
+	otherRecorder := newHeaderRecorder("PRIVATE-TOKEN")
+	other := httptest.NewServer(otherRecorder.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("external " + r.URL.Path))
+	})))
+	defer other.Close()
+
+	recorder := newHeaderRecorder("PRIVATE-TOKEN")
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/{project}/releases/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("project") != "group/sub/project" || r.PathValue("tag") != "v2.0" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"assets": map[string]any{"links": []map[string]string{
+			{"name": "local.bin", "url": server.URL + "/ignored", "direct_asset_url": server.URL + "/group/sub/project/-/releases/v2.0/downloads/local.bin"},
+			{"name": "external.bin", "url": other.URL + "/external.bin"},
+			{"name": "redirected.bin", "url": server.URL + "/redirect"},
+		}}})
+	})
+	mux.HandleFunc("GET /group/sub/project/-/releases/v2.0/downloads/local.bin", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("local"))
+	})
+	mux.HandleFunc("GET /redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/redirected.bin", http.StatusFound)
+	})
+	server = httptest.NewServer(recorder.wrap(mux))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	t.Setenv("GITLAB_HOSTS", strings.Split(host, ":")[0])
+	src, err := parseSource(server.URL + "/group/sub/project/-/releases/v2.0")
+	if err != nil {
+		t.Fatalf("parseSource() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	assets, err := downloadReleaseAssets(src, "*", dir)
+	if err != nil {
+		t.Fatalf("downloadReleaseAssets() error = %v", err)
+	}
+	if len(assets) != 3 {
+		t.Fatalf("downloadReleaseAssets() = %+v", assets)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "redirected.bin")); string(data) != "external /redirected.bin" {
+		t.Errorf("redirected.bin = %q", data)
+	}
+
+	tests := []struct {
+		recorder *headerRecorder
+		path     string
+		want     string
+	}{
+		{recorder, "/api/v4/projects/group/sub/project/releases/v2.0", "token"},
+		{recorder, "/group/sub/project/-/releases/v2.0/downloads/local.bin", "token"},
+		{recorder, "/redirect", "token"},
+		{otherRecorder, "/external.bin", ""},
+		{otherRecorder, "/redirected.bin", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.recorder.get(tt.path); got != tt.want {
+			t.Errorf("PRIVATE-TOKEN for %s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDropAPIAuthOnRedirect(t *testing.T) {
+	tests := []struct {
+		from, to string
+		keep     bool
+	}{
+		{"https://gitlab.example.com/api", "https://gitlab.example.com/file", true},
+		{"https://gitlab.example.com/api", "https://cdn.example.com/file", false},
+		{"https://gitlab.example.com/api", "http://gitlab.example.com/file", false},
+		{"https://gitlab.example.com/api", "https://gitlab.example.com:8443/file", false},
+	}
+	for _, tt := range tests {
+		original, _ := http.NewRequest(http.MethodGet, tt.from, nil)
+		redirect, _ := http.NewRequest(http.MethodGet, tt.to, nil)
+		redirect.Header.Set("PRIVATE-TOKEN", "token")
+		redirect.Header.Set("Authorization", "Bearer token")
+		if err := dropAPIAuthOnRedirect(redirect, []*http.Request{original}); err != nil {
+			t.Fatalf("dropAPIAuthOnRedirect() error = %v", err)
+		}
+		kept := redirect.Header.Get("PRIVATE-TOKEN") != "" || redirect.Header.Get("Authorization") != ""
+		if kept != tt.keep {
+			t.Errorf("redirect %s -> %s kept credentials = %v, want %v", tt.from, tt.to, kept, tt.keep)
+		}
+	}
+
+	via := make([]*http.Request, 10)
+	via[0], _ = http.NewRequest(http.MethodGet, "https://gitlab.example.com/", nil)
+	redirect, _ := http.NewRequest(http.MethodGet, "https://gitlab.example.com/", nil)
+	if err := dropAPIAuthOnRedirect(redirect, via); err == nil {
+		t.Errorf("dropAPIAuthOnRedirect() allowed an 11th redirect")
+	}
+}
@@ -0,0 +1,230 @@
+// fetch/revision.go
+package fetch
+
+import (
+	"fmt"
+	"mygitapp/logger"
+	"regexp"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RefType classifies what a revision was resolved through.
+type RefType string
+
+const (
+	RefBranch       RefType = "branch"        // local branch (refs/heads/*)
+	RefRemoteBranch RefType = "remote-branch" // remote-tracking branch (refs/remotes/*)
+	RefTag          RefType = "tag"           // lightweight or annotated tag (refs/tags/*)
+	RefCommit       RefType = "commit"        // commit SHA or revision expression
+	RefSymbolic     RefType = "symbolic"      // symbolic reference such as a remote's HEAD
+	RefOther        RefType = "ref"           // any other reference, e.g. refs/pull/*
+)
+
+// defaultRemote is the remote name used by clones made by this package.
+const defaultRemote = "origin"
+
+// Revision is a revision spec resolved to a commit.
+type Revision struct {
+	Spec string                 // the revision as given by the user
+	Name plumbing.ReferenceName // reference the revision resolved through, empty for commits
+	Type RefType                // kind of reference the revision resolved through
+	Hash plumbing.Hash          // commit the revision points to, with tags peeled
+	Tag  *object.Tag            // annotated tag object, if Name is an annotated tag
+}
+
+// hashPrefixPattern matches full and abbreviated hexadecimal object names.
+var hashPrefixPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// peelSuffixes are revision suffixes that only peel a tag to its commit.
+var peelSuffixes = map[string]bool{"": true, "^{}": true, "^{commit}": true, "^0": true}
+
+// ResolveRevision resolves a revision spec to a commit. It accepts local and
+// remote-tracking branches, lightweight and annotated tags, full and short SHAs,
+// full reference names, and expressions such as "main~3" or "v1.2^{commit}".
+//
+// Bare names are looked up as a local branch, a branch of the "origin" remote,
+// a tag and finally any other reference, matching the way "git checkout" picks
+// a branch before a tag.
+func ResolveRevision(repo *git.Repository, spec string) (*Revision, error) {
+	base, suffix := splitRevision(spec)
+
+	rev, err := resolveBase(repo, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %q: %v", spec, err)
+	}
+	rev.Spec = spec
+
+	if !peelSuffixes[suffix] {
+		hash, err := repo.ResolveRevision(plumbing.Revision(rev.Hash.String() + suffix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve revision %q: %v", spec, err)
+		}
+		// An expression such as main~3 no longer refers to the reference itself
+		rev = &Revision{Spec: spec, Type: RefCommit, Hash: *hash}
+	}
+
+	logger.Log.Debugf("Resolved revision %s to %s %s", spec, rev.Type, rev.Hash)
+	return rev, nil
+}
+
+// splitRevision splits a revision spec into the reference or hash it starts
+// with and the navigation suffix that follows it (e.g. "main" and "~3").
+func splitRevision(spec string) (string, string) {
+	end := len(spec)
+	for _, marker := range []string{"~", "^", "@{", ":"} {
+		if i := strings.Index(spec, marker); i >= 0 && i < end {
+			end = i
+		}
+	}
+	base, suffix := spec[:end], spec[end:]
+	if base == "" || base == "@" {
+		base = string(plumbing.HEAD)
+	}
+	return base, suffix
+}
+
+// resolveBase resolves a reference name or hash prefix to a commit.
+func resolveBase(repo *git.Repository, base string) (*Revision, error) {
+	if base == string(plumbing.HEAD) {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		if head.Name().IsBranch() {
+			return revisionForRef(repo, head)
+		}
+		return &Revision{Type: RefCommit, Hash: head.Hash()}, nil
+	}
+
+	for _, name := range candidateRefNames(base) {
+		ref, err := repo.Reference(name, true)
+		if err == nil {
+			return revisionForRef(repo, ref)
+		}
+	}
+
+	if hashPrefixPattern.MatchString(base) {
+		hash, err := repo.ResolveRevision(plumbing.Revision(base))
+		if err == nil {
+			return &Revision{Type: RefCommit, Hash: *hash}, nil
+		}
+	}
+	return nil, plumbing.ErrReferenceNotFound
+}
+
+// candidateRefNames lists the references a bare name may refer to, in lookup order.
+func candidateRefNames(name string) []plumbing.ReferenceName {
+	if strings.HasPrefix(name, "refs/") {
+		return []plumbing.ReferenceName{plumbing.ReferenceName(name)}
+	}
+	return []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(name),
+		plumbing.NewRemoteReferenceName(defaultRemote, name),
+		plumbing.NewTagReferenceName(name),
+		plumbing.ReferenceName("refs/remotes/" + name),
+		plumbing.ReferenceName("refs/" + name),
+	}
+}
+
+// revisionForRef builds a Revision for ref, peeling annotated tags to their commit.
+func revisionForRef(repo *git.Repository, ref *plumbing.Reference) (*Revision, error) {
+	rev := &Revision{Name: ref.Name(), Hash: ref.Hash(), Type: RefCommit}
+	switch {
+	case ref.Name().IsBranch():
+		rev.Type = RefBranch
+	case ref.Name().IsRemote():
+		rev.Type = RefRemoteBranch
+	case ref.Name().IsTag():
+		rev.Type = RefTag
+	}
+
+	// Peel annotated tags (including tags of tags) down to the commit
+	for {
+		tag, err := repo.TagObject(rev.Hash)
+		if err != nil {
+			break
+		}
+		if rev.Tag == nil {
+			rev.Tag = tag
+		}
+		rev.Hash = tag.Target
+	}
+
+	if _, err := repo.CommitObject(rev.Hash); err != nil {
+		return nil, fmt.Errorf("%s does not point to a commit: %v", ref.Name(), err)
+	}
+	return rev, nil
+}
+
+// checkoutRevision resolves spec, which may be a version selector, and checks
+// it out. Branches are checked out as local branches, creating one from the
+// remote-tracking branch if needed; everything else results in a detached
+// HEAD at the resolved commit.
+func checkoutRevision(repo *git.Repository, spec string, prerelease bool) (*Revision, error) {
+	rev, err := ResolveSelector(repo, spec, prerelease)
+	if err != nil {
+		logger.Log.WithError(err).Errorf("Failed to resolve %s", spec)
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get worktree")
+		return nil, fmt.Errorf("failed to get worktree: %v", err)
+	}
+
+	checkoutOptions := &git.CheckoutOptions{Hash: rev.Hash}
+	switch rev.Type {
+	case RefBranch:
+		checkoutOptions = &git.CheckoutOptions{Branch: rev.Name}
+	case RefRemoteBranch:
+		tracking, err := createTrackingBranch(repo, rev)
+		if err != nil {
+			return nil, err
+		}
+		if tracking {
+			checkoutOptions = &git.CheckoutOptions{Branch: rev.Name}
+		}
+	}
+
+	if err := worktree.Checkout(checkoutOptions); err != nil {
+		logger.Log.WithError(err).Errorf("Failed to checkout %s", spec)
+		return nil, fmt.Errorf("failed to checkout %s: %v", spec, err)
+	}
+	if IsVersionSelector(spec) && rev.Name != "" {
+		logger.Log.Infof("Resolved %s to %s", spec, rev.Name.Short())
+	}
+	logger.Log.Infof("Checked out %s %s at %s", rev.Type, spec, rev.Hash)
+	return rev, nil
+}
+
+// createTrackingBranch creates a local branch for the remote-tracking branch
+// rev points to and updates rev to refer to it. It reports false, leaving rev
+// untouched, when a local branch of that name already exists elsewhere.
+func createTrackingBranch(repo *git.Repository, rev *Revision) (bool, error) {
+	remote, branch, _ := strings.Cut(strings.TrimPrefix(rev.Name.String(), "refs/remotes/"), "/")
+	localName := plumbing.NewBranchReferenceName(branch)
+
+	if local, err := repo.Reference(localName, false); err == nil {
+		if local.Hash() != rev.Hash {
+			return false, nil
+		}
+	} else {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(localName, rev.Hash)); err != nil {
+			return false, fmt.Errorf("failed to create branch %s: %v", branch, err)
+		}
+		err := repo.CreateBranch(&config.Branch{Name: branch, Remote: remote, Merge: localName})
+		if err != nil && err != git.ErrBranchExists {
+			return false, fmt.Errorf("failed to configure branch %s: %v", branch, err)
+		}
+	}
+
+	rev.Name = localName
+	rev.Type = RefBranch
+	return true, nil
+}
@@ -0,0 +1,143 @@
+// fetch/revision_test.go
+package fetch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var testSignature = &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1700000000, 0)}
+
+// newTestRepo initializes a repository with a worktree in a temporary directory.
+func newTestRepo(t *testing.T) *git.Repository {
+	t.Helper()
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to init repository: %v", err)
+	}
+	return repo
+}
+
+// commitFile writes content to path in the worktree of repo and commits it.
+func commitFile(t *testing.T, repo *git.Repository, path, content string) plumbing.Hash {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := filepath.Join(worktree.Filesystem.Root(), path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(path); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit("update "+path, &git.CommitOptions{Author: testSignature})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	return hash
+}
+
+// setRef points name at hash.
+func setRef(t *testing.T, repo *git.Repository, name string, hash plumbing.Hash) {
+	t.Helper()
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), hash)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveRevision(t *testing.T) {
+	repo := newTestRepo(t)
+	first := commitFile(t, repo, "a.txt", "one\n")
+	second := commitFile(t, repo, "a.txt", "two\n")
+	third := commitFile(t, repo, "a.txt", "three\n")
+
+	setRef(t, repo, "refs/heads/feature", second)
+	setRef(t, repo, "refs/remotes/origin/release", first)
+	setRef(t, repo, "refs/tags/v1.0.0", first)
+	setRef(t, repo, "refs/pull/7/head", second)
+	tag, err := repo.CreateTag("v2.0.0", second, &git.CreateTagOptions{Tagger: testSignature, Message: "v2.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A tag sharing a branch's name resolves to the branch, as in git checkout
+	setRef(t, repo, "refs/tags/feature", first)
+
+	tests := []struct {
+		spec      string
+		wantName  string
+		wantType  RefType
+		wantHash  plumbing.Hash
+		annotated bool
+	}{
+		{"master", "refs/heads/master", RefBranch, third, false},
+		{"HEAD", "refs/heads/master", RefBranch, third, false},
+		{"feature", "refs/heads/feature", RefBranch, second, false},
+		{"release", "refs/remotes/origin/release", RefRemoteBranch, first, false},
+		{"origin/release", "refs/remotes/origin/release", RefRemoteBranch, first, false},
+		{"v1.0.0", "refs/tags/v1.0.0", RefTag, first, false},
+		{"v2.0.0", "refs/tags/v2.0.0", RefTag, second, true},
+		{"v2.0.0^{}", "refs/tags/v2.0.0", RefTag, second, true},
+		{"refs/tags/feature", "refs/tags/feature", RefTag, first, false},
+		{"pull/7/head", "refs/pull/7/head", RefCommit, second, false},
+		{third.String(), "", RefCommit, third, false},
+		{second.String()[:7], "", RefCommit, second, false},
+		{"master~2", "", RefCommit, first, false},
+		{"HEAD^", "", RefCommit, second, false},
+		{"v2.0.0~1", "", RefCommit, first, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rev, err := ResolveRevision(repo, tt.spec)
+			if err != nil {
+				t.Fatalf("ResolveRevision(%q) error = %v", tt.spec, err)
+			}
+			if string(rev.Name) != tt.wantName || rev.Type != tt.wantType || rev.Hash != tt.wantHash {
+				t.Errorf("ResolveRevision(%q) = %s %s %s, want %s %s %s", tt.spec, rev.Name, rev.Type, rev.Hash, tt.wantName, tt.wantType, tt.wantHash)
+			}
+			if (rev.Tag != nil) != tt.annotated {
+				t.Errorf("ResolveRevision(%q) tag = %v, want annotated %v", tt.spec, rev.Tag, tt.annotated)
+			}
+			if tt.annotated && rev.Tag.Hash != tag.Hash() {
+				t.Errorf("ResolveRevision(%q) tag = %s, want %s", tt.spec, rev.Tag.Hash, tag.Hash())
+			}
+		})
+	}
+
+	for _, spec := range []string{"missing", "master~10", "deadbeef"} {
+		if _, err := ResolveRevision(repo, spec); err == nil {
+			t.Errorf("ResolveRevision(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestSplitRevision(t *testing.T) {
+	tests := []struct {
+		spec, base, suffix string
+	}{
+		{"main", "main", ""},
+		{"main~3", "main", "~3"},
+		{"v1.2^{commit}", "v1.2", "^{commit}"},
+		{"main@{upstream}", "main", "@{upstream}"},
+		{"@", "HEAD", ""},
+		{"@~1", "HEAD", "~1"},
+		{"^{}", "HEAD", "^{}"},
+		{"feature/x^2~1", "feature/x", "^2~1"},
+	}
+	for _, tt := range tests {
+		base, suffix := splitRevision(tt.spec)
+		if base != tt.base || suffix != tt.suffix {
+			t.Errorf("splitRevision(%q) = %q, %q, want %q, %q", tt.spec, base, suffix, tt.base, tt.suffix)
+		}
+	}
+}
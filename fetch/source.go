@@ -0,0 +1,266 @@
+// fetch/source.go
+package fetch
+
+import (
+	"fmt"
+	"mygitapp/logger"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Providers recognised by parseSource.
+const (
+	providerGitHub      = "github"
+	providerGitLab      = "gitlab"
+	providerAzureDevOps = "azure-devops"
+	providerGeneric     = "git"
+)
+
+// source is a repository URI broken down into what to clone and what to check out.
+type source struct {
+	provider  string   // hosting provider the URI was recognised as
+	cloneURL  string   // URL handed to git clone
+	ref       string   // revision to check out after cloning, empty for the default branch
+	refPath   bool     // ref comes from a web URL and may be followed by a file path
+	path      string   // folder to restrict the checkout to, empty for the whole tree
+	release   string   // tag of the release a /releases/ URL points to
+	project   string   // owner/repo on GitHub or the project path on GitLab, used for API calls
+	parsedURL *url.URL // the URI as given, with scp-like SSH syntax normalised
+}
+
+// repoPath returns the path component of the clone URL, from which the repository name is derived.
+func (s *source) repoPath() string {
+	cloneURL, err := parseGitURI(s.cloneURL)
+	if err != nil {
+		return s.parsedURL.Path
+	}
+	return cloneURL.Path
+}
+
+// scpLikeURI matches scp-like SSH syntax such as git@github.com:org/repo.git.
+var scpLikeURI = regexp.MustCompile(`^([A-Za-z0-9._-]+@)?([A-Za-z0-9.-]+):([^/].*)$`)
+
+// parseGitURI parses a Git URI, accepting scp-like SSH syntax as an ssh:// URL.
+func parseGitURI(gitRepoURI string) (*url.URL, error) {
+	if !strings.Contains(gitRepoURI, "://") {
+		if m := scpLikeURI.FindStringSubmatch(gitRepoURI); m != nil {
+			return url.Parse(fmt.Sprintf("ssh://%s%s/%s", m[1], m[2], m[3]))
+		}
+	}
+	return url.Parse(gitRepoURI)
+}
+
+// parseSource works out the clone URL and the revision to check out from a Git URI.
+// A trailing "#<ref>" selects the revision on any host, including unknown ones.
+func parseSource(gitRepoURI string) (*source, error) {
+	parsedURL, err := parseGitURI(gitRepoURI)
+	if err != nil {
+		logger.Log.WithError(err).Error("Invalid URL")
+		return nil, fmt.Errorf("invalid URL: %v", err)
+	}
+
+	fragmentRef := parsedURL.Fragment
+	parsedURL.Fragment = ""
+	cloneURI, _, _ := strings.Cut(gitRepoURI, "#")
+
+	var src *source
+	// Determine the platform based on the host
+	switch parsedURL.Host {
+	case "github.com":
+		src, err = parseGitHubURL(cloneURI, parsedURL)
+	case "dev.azure.com", "ssh.dev.azure.com":
+		src, err = parseAzureDevOpsURL(parsedURL)
+	case "gitlab.com":
+		src, err = parseGitLabURL(parsedURL)
+	default:
+		if isAzureDevOpsHost(parsedURL.Hostname()) {
+			src, err = parseAzureDevOpsURL(parsedURL)
+			break
+		}
+		if isGitLabHost(parsedURL.Hostname()) {
+			src, err = parseGitLabURL(parsedURL)
+			break
+		}
+		// Standard Git URIs are cloned as given
+		src = &source{provider: providerGeneric, cloneURL: cloneURI}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	src.parsedURL = parsedURL
+	if fragmentRef != "" {
+		src.ref = fragmentRef
+	}
+	return src, nil
+}
+
+// parseGitHubURL handles GitHub's tree (branch, tag or commit), commit and releases (tag) URLs.
+func parseGitHubURL(cloneURI string, parsedURL *url.URL) (*source, error) {
+	pathSegments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	if len(pathSegments) < 3 {
+		// Plain repository URL
+		return &source{provider: providerGitHub, cloneURL: cloneURI}, nil
+	}
+
+	// Construct the base Git URL (e.g., https://github.com/user/repo.git)
+	owner, repo := pathSegments[0], strings.TrimSuffix(pathSegments[1], ".git")
+	src := &source{provider: providerGitHub, cloneURL: fmt.Sprintf("https://github.com/%s/%s.git", owner, repo), project: owner + "/" + repo}
+	if parsedURL.Scheme == "ssh" {
+		src.cloneURL = fmt.Sprintf("git@github.com:%s/%s.git", owner, repo)
+	}
+
+	switch {
+	case pathSegments[2] == "tree" && len(pathSegments) > 3:
+		// Branch, tag or commit, optionally followed by a path; branch names may contain slashes
+		src.ref = strings.Join(pathSegments[3:], "/")
+		src.refPath = true
+	case pathSegments[2] == "commit" && len(pathSegments) > 3:
+		src.ref = pathSegments[3]
+	case pathSegments[2] == "releases" && len(pathSegments) > 4 && pathSegments[3] == "tag":
+		src.ref = plumbing.NewTagReferenceName(pathSegments[4]).String()
+		src.release = pathSegments[4]
+	default:
+		logger.Log.Error("Unsupported GitHub URL structure")
+		return nil, fmt.Errorf("unsupported GitHub URL structure")
+	}
+	return src, nil
+}
+
+// isAzureDevOpsHost reports whether host is a legacy {organization}.visualstudio.com host
+// or one of the Azure DevOps Server hosts listed in the AZURE_DEVOPS_HOSTS environment variable.
+func isAzureDevOpsHost(host string) bool {
+	host = strings.ToLower(host)
+	return strings.HasSuffix(host, ".visualstudio.com") || hostListed("AZURE_DEVOPS_HOSTS", host)
+}
+
+// parseAzureDevOpsURL handles Azure DevOps Services, legacy visualstudio.com and Azure DevOps Server URLs.
+func parseAzureDevOpsURL(parsedURL *url.URL) (*source, error) {
+	// Example Azure DevOps HTTPS URL: https://dev.azure.com/{organization}/{project}/_git/{repo}?version=GB{branch}, GT{tag} or GC{commit}&path=/{folder}
+	// Example legacy HTTPS URL: https://{organization}.visualstudio.com/{project}/_git/{repo}
+	// Example Azure DevOps Server URL: https://{server}/tfs/{collection}/{project}/_git/{repo}
+	// Example Azure DevOps SSH URL: git@ssh.dev.azure.com:v3/{organization}/{project}/{repo}
+
+	// Parse query parameters for branch, tag or commit
+	query := parsedURL.Query()
+	version := query.Get("version") // e.g., GBbranch, GTtag or GCsha
+
+	src := &source{provider: providerAzureDevOps, path: strings.Trim(query.Get("path"), "/")}
+	if strings.HasPrefix(version, "GB") {
+		src.ref = strings.TrimPrefix(version, "GB")
+	} else if strings.HasPrefix(version, "GT") {
+		src.ref = plumbing.NewTagReferenceName(strings.TrimPrefix(version, "GT")).String()
+	} else if strings.HasPrefix(version, "GC") {
+		src.ref = strings.TrimPrefix(version, "GC")
+	}
+
+	pathSegments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	gitIndex := slices.Index(pathSegments, "_git")
+
+	switch {
+	case gitIndex >= 1 && gitIndex+1 < len(pathSegments):
+		// HTTPS (or on-premises SSH) URL: /[tfs/]{organization or collection}/[{project}/]_git/{repo}
+		// The project may be omitted when the repository is named after it.
+		repoPath := strings.Join(pathSegments[:gitIndex+2], "/")
+		if parsedURL.Scheme == "ssh" {
+			src.cloneURL = fmt.Sprintf("ssh://%s@%s/%s", sshUser(parsedURL), parsedURL.Host, repoPath)
+		} else {
+			src.cloneURL = fmt.Sprintf("%s://%s/%s", parsedURL.Scheme, parsedURL.Host, repoPath)
+		}
+	case parsedURL.Scheme == "ssh" && len(pathSegments) >= 4 && pathSegments[0] == "v3":
+		// SSH URL: v3/{organization}/{project}/{repo}
+		// (ssh.dev.azure.com, or vs-ssh.visualstudio.com where the user is the organization)
+		src.cloneURL = fmt.Sprintf("%s@%s:%s", sshUser(parsedURL), parsedURL.Host, strings.Join(pathSegments[:4], "/"))
+	case parsedURL.Scheme == "ssh":
+		logger.Log.Error("Invalid Azure DevOps SSH URL structure")
+		return nil, fmt.Errorf("invalid Azure DevOps SSH URL structure")
+	default:
+		logger.Log.Error("Invalid Azure DevOps HTTPS URL structure")
+		return nil, fmt.Errorf("invalid Azure DevOps HTTPS URL structure")
+	}
+	return src, nil
+}
+
+// isGitLabHost reports whether host is gitlab.com or one of the self-hosted
+// GitLab instances listed in the comma-separated GITLAB_HOSTS environment variable.
+func isGitLabHost(host string) bool {
+	return strings.EqualFold(host, "gitlab.com") || hostListed("GITLAB_HOSTS", host)
+}
+
+// hostListed reports whether host appears in the comma-separated host list held by envVar.
+func hostListed(envVar, host string) bool {
+	for _, listed := range strings.Split(os.Getenv(envVar), ",") {
+		if listed = strings.TrimSpace(listed); listed != "" && strings.EqualFold(listed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGitLabURL handles gitlab.com and self-hosted GitLab URLs, with any depth of subgroups.
+func parseGitLabURL(parsedURL *url.URL) (*source, error) {
+	// Example GitLab HTTPS URL: https://gitlab.com/{group}/{subgroup...}/{project}.git?ref={branch or tag}
+	// Example GitLab web URLs: https://gitlab.com/{group}/{project}/-/tree/{ref}, /-/tags/{tag}, /-/releases/{tag} or /-/commit/{sha}
+	// Example GitLab SSH URL: git@gitlab.com:{group}/{subgroup...}/{project}.git
+
+	// Parse query parameters for branch or tag. GitLab doesn't differentiate between
+	// branch and tag in the ref parameter, so it is resolved as a branch first, then as a tag.
+	query := parsedURL.Query()
+	src := &source{provider: providerGitLab, ref: query.Get("ref")}
+
+	// Everything before "/-/" is the project path, everything after it is the web UI route
+	projectPath, webPath, isWebURL := strings.Cut(strings.Trim(parsedURL.Path, "/"), "/-/")
+	if isWebURL {
+		webSegments := strings.Split(webPath, "/")
+		switch {
+		case webSegments[0] == "tree" && len(webSegments) > 1:
+			// Branch, tag or commit, optionally followed by a path
+			src.ref = strings.Join(webSegments[1:], "/")
+			src.refPath = true
+		case webSegments[0] == "tags" && len(webSegments) > 1:
+			src.ref = plumbing.NewTagReferenceName(strings.Join(webSegments[1:], "/")).String()
+		case webSegments[0] == "releases" && len(webSegments) > 1:
+			src.release = strings.Join(webSegments[1:], "/")
+			src.ref = plumbing.NewTagReferenceName(src.release).String()
+		case webSegments[0] == "commit" && len(webSegments) > 1:
+			src.ref = webSegments[1]
+		default:
+			logger.Log.Error("Unsupported GitLab URL structure")
+			return nil, fmt.Errorf("unsupported GitLab URL structure")
+		}
+	}
+
+	// The project path is {group}/{subgroup...}/{project}
+	projectPath = strings.TrimSuffix(projectPath, ".git")
+	if len(strings.Split(projectPath, "/")) < 2 {
+		logger.Log.Error("Invalid GitLab URL structure")
+		return nil, fmt.Errorf("invalid GitLab URL structure")
+	}
+	src.project = projectPath
+
+	// Construct the base Git URL
+	switch {
+	case parsedURL.Scheme == "ssh" && parsedURL.Port() != "":
+		src.cloneURL = fmt.Sprintf("ssh://%s@%s/%s.git", sshUser(parsedURL), parsedURL.Host, projectPath)
+	case parsedURL.Scheme == "ssh":
+		src.cloneURL = fmt.Sprintf("%s@%s:%s.git", sshUser(parsedURL), parsedURL.Host, projectPath)
+	case parsedURL.Scheme == "http":
+		src.cloneURL = fmt.Sprintf("http://%s/%s.git", parsedURL.Host, projectPath)
+	default:
+		src.cloneURL = fmt.Sprintf("https://%s/%s.git", parsedURL.Host, projectPath)
+	}
+	return src, nil
+}
+
+// sshUser returns the user of an SSH URL, defaulting to "git".
+func sshUser(parsedURL *url.URL) string {
+	if parsedURL.User != nil && parsedURL.User.Username() != "" {
+		return parsedURL.User.Username()
+	}
+	return "git"
+}
@@ -0,0 +1,75 @@
+// fetch/source_test.go
+package fetch
+
+import "testing"
+
+func TestParseSource(t *testing.T) {
+	t.Setenv("GITLAB_HOSTS", "git.example.com, code.example.org")
+	t.Setenv("AZURE_DEVOPS_HOSTS", "tfs.example.com")
+
+	tests := []struct {
+		uri      string
+		provider string
+		cloneURL string
+		ref      string
+		refPath  bool
+		path     string
+		release  string
+		project  string
+	}{
+		// GitHub
+		{uri: "https://github.com/org/repo.git", provider: providerGitHub, cloneURL: "https://github.com/org/repo.git"},
+		{uri: "git@github.com:org/repo.git", provider: providerGitHub, cloneURL: "git@github.com:org/repo.git"},
+		{uri: "https://github.com/org/repo/tree/feature/x/docs", provider: providerGitHub, cloneURL: "https://github.com/org/repo.git", ref: "feature/x/docs", refPath: true, project: "org/repo"},
+		{uri: "https://github.com/org/repo/commit/0123abc", provider: providerGitHub, cloneURL: "https://github.com/org/repo.git", ref: "0123abc", project: "org/repo"},
+		{uri: "https://github.com/org/repo/releases/tag/v1.2.0", provider: providerGitHub, cloneURL: "https://github.com/org/repo.git", ref: "refs/tags/v1.2.0", release: "v1.2.0", project: "org/repo"},
+		{uri: "https://github.com/org/repo.git#v1.0", provider: providerGitHub, cloneURL: "https://github.com/org/repo.git", ref: "v1.0"},
+
+		// Azure DevOps
+		{uri: "https://dev.azure.com/org/proj/_git/repo?version=GBmain&path=/src/", provider: providerAzureDevOps, cloneURL: "https://dev.azure.com/org/proj/_git/repo", ref: "main", path: "src"},
+		{uri: "https://dev.azure.com/org/proj/_git/repo?version=GTv1.0", provider: providerAzureDevOps, cloneURL: "https://dev.azure.com/org/proj/_git/repo", ref: "refs/tags/v1.0"},
+		{uri: "https://dev.azure.com/org/proj/_git/repo?version=GC0123abc", provider: providerAzureDevOps, cloneURL: "https://dev.azure.com/org/proj/_git/repo", ref: "0123abc"},
+		{uri: "https://org.visualstudio.com/proj/_git/repo", provider: providerAzureDevOps, cloneURL: "https://org.visualstudio.com/proj/_git/repo"},
+		{uri: "https://tfs.example.com/tfs/coll/proj/_git/repo", provider: providerAzureDevOps, cloneURL: "https://tfs.example.com/tfs/coll/proj/_git/repo"},
+		{uri: "git@ssh.dev.azure.com:v3/org/proj/repo", provider: providerAzureDevOps, cloneURL: "git@ssh.dev.azure.com:v3/org/proj/repo"},
+
+		// GitLab
+		{uri: "https://gitlab.com/group/sub/project.git?ref=develop", provider: providerGitLab, cloneURL: "https://gitlab.com/group/sub/project.git", ref: "develop", project: "group/sub/project"},
+		{uri: "https://gitlab.com/group/project/-/tree/main/docs", provider: providerGitLab, cloneURL: "https://gitlab.com/group/project.git", ref: "main/docs", refPath: true, project: "group/project"},
+		{uri: "https://gitlab.com/group/project/-/tags/v2.0", provider: providerGitLab, cloneURL: "https://gitlab.com/group/project.git", ref: "refs/tags/v2.0", project: "group/project"},
+		{uri: "https://gitlab.com/group/project/-/releases/v2.0", provider: providerGitLab, cloneURL: "https://gitlab.com/group/project.git", ref: "refs/tags/v2.0", release: "v2.0", project: "group/project"},
+		{uri: "https://gitlab.com/group/project/-/commit/0123abc", provider: providerGitLab, cloneURL: "https://gitlab.com/group/project.git", ref: "0123abc", project: "group/project"},
+		{uri: "git@gitlab.com:group/sub/project.git", provider: providerGitLab, cloneURL: "git@gitlab.com:group/sub/project.git", project: "group/sub/project"},
+		{uri: "ssh://git@git.example.com:2222/group/project.git", provider: providerGitLab, cloneURL: "ssh://git@git.example.com:2222/group/project.git", project: "group/project"},
+		{uri: "http://code.example.org/group/project", provider: providerGitLab, cloneURL: "http://code.example.org/group/project.git", project: "group/project"},
+
+		// Other hosts
+		{uri: "https://git.other.com/repo.git#main", provider: providerGeneric, cloneURL: "https://git.other.com/repo.git", ref: "main"},
+		{uri: "/srv/git/repo.git", provider: providerGeneric, cloneURL: "/srv/git/repo.git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			src, err := parseSource(tt.uri)
+			if err != nil {
+				t.Fatalf("parseSource(%q) error = %v", tt.uri, err)
+			}
+			if src.provider != tt.provider || src.cloneURL != tt.cloneURL || src.ref != tt.ref || src.refPath != tt.refPath ||
+				src.path != tt.path || src.release != tt.release || src.project != tt.project {
+				t.Errorf("parseSource(%q) = %+v", tt.uri, *src)
+			}
+		})
+	}
+
+	for _, uri := range []string{
+		"https://github.com/org/repo/issues/1",
+		"https://dev.azure.com/org/proj/repo",
+		"git@ssh.dev.azure.com:org/repo",
+		"https://gitlab.com/project",
+		"https://gitlab.com/group/project/-/issues/1",
+		"https://github.com/%zz",
+	} {
+		if _, err := parseSource(uri); err == nil {
+			t.Errorf("parseSource(%q) succeeded, want an error", uri)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// fetch/sshsig.go
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshSigMagic      = "SSHSIG"
+	sshSigNamespace  = "git"
+	sshSigBeginArmor = "-----BEGIN SSH SIGNATURE-----"
+	sshSigEndArmor   = "-----END SSH SIGNATURE-----"
+)
+
+// sshSignature is the wire format of an SSHSIG blob (after the magic preamble).
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is the structure that is actually signed by ssh-keygen -Y sign.
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// allowedSigner is a single entry of an SSH allowed-signers file.
+type allowedSigner struct {
+	Principals []string
+	Namespaces []string
+	Key        ssh.PublicKey
+}
+
+// parseSSHSignature decodes an armored SSH signature as produced by "ssh-keygen -Y sign".
+func parseSSHSignature(armored string) (*sshSignature, error) {
+	armored = strings.TrimSpace(armored)
+	if !strings.HasPrefix(armored, sshSigBeginArmor) || !strings.HasSuffix(armored, sshSigEndArmor) {
+		return nil, fmt.Errorf("malformed SSH signature armor")
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(armored, sshSigBeginArmor), sshSigEndArmor)
+	blob, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SSH signature: %v", err)
+	}
+	if !bytes.HasPrefix(blob, []byte(sshSigMagic)) {
+		return nil, fmt.Errorf("SSH signature is missing the %s preamble", sshSigMagic)
+	}
+
+	sig := &sshSignature{}
+	if err := ssh.Unmarshal(blob[len(sshSigMagic):], sig); err != nil {
+		return nil, fmt.Errorf("failed to parse SSH signature: %v", err)
+	}
+	if sig.Version != 1 {
+		return nil, fmt.Errorf("unsupported SSH signature version %d", sig.Version)
+	}
+	return sig, nil
+}
+
+// verifySSHSignature checks an armored SSH signature over message and returns the signing key.
+func verifySSHSignature(armored string, message []byte) (ssh.PublicKey, error) {
+	sig, err := parseSSHSignature(armored)
+	if err != nil {
+		return nil, err
+	}
+	if sig.Namespace != sshSigNamespace {
+		return nil, fmt.Errorf("unexpected SSH signature namespace %q", sig.Namespace)
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("unsupported SSH signature hash algorithm %q", sig.HashAlgorithm)
+	}
+	h.Write(message)
+
+	publicKey, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH signature public key: %v", err)
+	}
+
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(sig.Signature, signature); err != nil {
+		return nil, fmt.Errorf("failed to parse SSH signature blob: %v", err)
+	}
+
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+	if err := publicKey.Verify(signed, signature); err != nil {
+		return nil, fmt.Errorf("SSH signature verification failed: %v", err)
+	}
+	return publicKey, nil
+}
+
+// loadAllowedSigners parses an SSH allowed-signers file (see ssh-keygen(1), ALLOWED SIGNERS).
+func loadAllowedSigners(path string) ([]allowedSigner, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open allowed signers file: %v", err)
+	}
+	defer file.Close()
+
+	var signers []allowedSigner
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The first field lists the principals, the rest has the authorized_keys layout
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("allowed signers line %d: missing public key", lineNo)
+		}
+		key, _, options, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(fields[1])))
+		if err != nil {
+			return nil, fmt.Errorf("allowed signers line %d: %v", lineNo, err)
+		}
+
+		signer := allowedSigner{
+			Principals: strings.Split(strings.Trim(fields[0], `"`), ","),
+			Key:        key,
+		}
+		for _, option := range options {
+			if value, ok := strings.CutPrefix(option, "namespaces="); ok {
+				signer.Namespaces = strings.Split(strings.Trim(value, `"`), ",")
+			}
+		}
+		signers = append(signers, signer)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read allowed signers file: %v", err)
+	}
+	return signers, nil
+}
+
+// findAllowedSigner returns the allowed-signers entry matching key for the git namespace.
+func findAllowedSigner(signers []allowedSigner, key ssh.PublicKey) *allowedSigner {
+	for i, signer := range signers {
+		if !bytes.Equal(signer.Key.Marshal(), key.Marshal()) {
+			continue
+		}
+		if len(signer.Namespaces) > 0 && !slices.Contains(signer.Namespaces, sshSigNamespace) {
+			continue
+		}
+		return &signers[i]
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// fetch/sshsig_test.go
+package fetch
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// signSSH produces an armored SSHSIG signature of message, as "ssh-keygen -Y sign" would.
+func signSSH(t *testing.T, signer ssh.Signer, namespace string, message []byte) string {
+	t.Helper()
+	digest := sha512.Sum512(message)
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSignedData{
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Hash:          digest[:],
+	})...)
+	signature, err := signer.Sign(rand.Reader, signed)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	blob := append([]byte(sshSigMagic), ssh.Marshal(sshSignature{
+		Version:       1,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var lines []string
+	for len(encoded) > 70 {
+		lines = append(lines, encoded[:70])
+		encoded = encoded[70:]
+	}
+	lines = append(lines, encoded)
+	return sshSigBeginArmor + "\n" + strings.Join(lines, "\n") + "\n" + sshSigEndArmor + "\n"
+}
+
+// newSSHSigner returns a fresh ed25519 signer.
+func newSSHSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
+func TestParseSSHSignature(t *testing.T) {
+	signer := newSSHSigner(t)
+	valid := signSSH(t, signer, sshSigNamespace, []byte("payload"))
+	body := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(valid), sshSigBeginArmor), sshSigEndArmor)
+
+	tests := []struct {
+		name    string
+		armored string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"surrounding whitespace", "\n  " + valid + "\n\n", ""},
+		{"missing armor", body, "malformed SSH signature armor"},
+		{"missing end armor", sshSigBeginArmor + body, "malformed SSH signature armor"},
+		{"invalid base64", sshSigBeginArmor + "\n!!!\n" + sshSigEndArmor, "failed to decode SSH signature"},
+		{"missing magic", sshSigBeginArmor + "\n" + base64.StdEncoding.EncodeToString([]byte("NOTSIG")) + "\n" + sshSigEndArmor, "missing the SSHSIG preamble"},
+		{"truncated blob", sshSigBeginArmor + "\n" + base64.StdEncoding.EncodeToString([]byte("SSHSIG\x00\x00")) + "\n" + sshSigEndArmor, "failed to parse SSH signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, err := parseSSHSignature(tt.armored)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseSSHSignature() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSSHSignature() error = %v", err)
+			}
+			if sig.Version != 1 || sig.Namespace != sshSigNamespace || sig.HashAlgorithm != "sha512" {
+				t.Errorf("parseSSHSignature() = %+v", sig)
+			}
+		})
+	}
+}
+
+func TestVerifySSHSignature(t *testing.T) {
+	signer := newSSHSigner(t)
+	message := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n")
+
+	tests := []struct {
+		name    string
+		armored string
+		message []byte
+		wantErr string
+	}{
+		{"valid", signSSH(t, signer, sshSigNamespace, message), message, ""},
+		{"tampered message", signSSH(t, signer, sshSigNamespace, message), []byte("tampered"), "verification failed"},
+		{"wrong namespace", signSSH(t, signer, "file", message), message, "unexpected SSH signature namespace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := verifySSHSignature(tt.armored, tt.message)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("verifySSHSignature() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifySSHSignature() error = %v", err)
+			}
+			if string(key.Marshal()) != string(signer.PublicKey().Marshal()) {
+				t.Errorf("verifySSHSignature() returned a different key")
+			}
+		})
+	}
+}
+
+func TestLoadAllowedSigners(t *testing.T) {
+	gitSigner := newSSHSigner(t)
+	fileSigner := newSSHSigner(t)
+	otherSigner := newSSHSigner(t)
+	authorized := func(s ssh.Signer) string {
+		return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(s.PublicKey())))
+	}
+
+	path := filepath.Join(t.TempDir(), "allowed_signers")
+	content := "# comment\n\n" +
+		"dev@example.com,ci@example.com " + authorized(gitSigner) + "\n" +
+		`ops@example.com namespaces="file" ` + authorized(fileSigner) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	signers, err := loadAllowedSigners(path)
+	if err != nil {
+		t.Fatalf("loadAllowedSigners() error = %v", err)
+	}
+	if len(signers) != 2 {
+		t.Fatalf("loadAllowedSigners() returned %d signers, want 2", len(signers))
+	}
+	if got := strings.Join(signers[0].Principals, ","); got != "dev@example.com,ci@example.com" {
+		t.Errorf("principals = %q", got)
+	}
+
+	tests := []struct {
+		name string
+		key  ssh.PublicKey
+		want string
+	}{
+		{"listed for git", gitSigner.PublicKey(), "dev@example.com"},
+		{"listed for another namespace", fileSigner.PublicKey(), ""},
+		{"not listed", otherSigner.PublicKey(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if signer := findAllowedSigner(signers, tt.key); signer != nil {
+				got = signer.Principals[0]
+			}
+			if got != tt.want {
+				t.Errorf("findAllowedSigner() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if err := os.WriteFile(path, []byte("nokey\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadAllowedSigners(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("loadAllowedSigners() error = %v, want a line 1 error", err)
+	}
+}
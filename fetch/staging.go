@@ -0,0 +1,114 @@
+// fetch/staging.go
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mygitapp/logger"
+	"os"
+	"path/filepath"
+	"slices"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+// checkTargetDir makes sure a fetch may write to targetDir: it must not exist,
+// be empty, or already hold a clone of cloneURL. Anything else is refused
+// unless force is set.
+func checkTargetDir(targetDir, cloneURL string, force bool) error {
+	info, err := os.Stat(targetDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect target directory %s: %v", targetDir, err)
+	}
+	if !info.IsDir() {
+		if force {
+			return nil
+		}
+		return fmt.Errorf("target %s exists and is not a directory", targetDir)
+	}
+
+	empty, err := isEmptyDir(targetDir)
+	if err != nil {
+		return fmt.Errorf("failed to inspect target directory %s: %v", targetDir, err)
+	}
+	if empty || force || isCloneOf(targetDir, cloneURL) {
+		return nil
+	}
+	return fmt.Errorf("target directory %s is not empty and is not a clone of %s (use force to overwrite it)", targetDir, cloneURL)
+}
+
+// isEmptyDir reports whether dir has no entries.
+func isEmptyDir(dir string) (bool, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, err = f.Readdirnames(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
+
+// isCloneOf reports whether dir is a repository whose origin remote is cloneURL.
+func isCloneOf(dir, cloneURL string) bool {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return false
+	}
+	remote, err := repo.Remote(defaultRemote)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(remote.Config().URLs, cloneURL)
+}
+
+// newStagingDir creates an empty directory next to targetDir to fetch into, so
+// that it can later be renamed into place on the same filesystem.
+func newStagingDir(targetDir string) (string, error) {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid target directory %s: %v", targetDir, err)
+	}
+	parent := filepath.Dir(absTarget)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create parent of target directory %s: %v", targetDir, err)
+	}
+	stagingDir, err := os.MkdirTemp(parent, "."+filepath.Base(absTarget)+".staging-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory for %s: %v", targetDir, err)
+	}
+	return stagingDir, nil
+}
+
+// promoteStagingDir moves a completed fetch from stagingDir to targetDir,
+// replacing whatever checkTargetDir allowed to be there. A replaced target is
+// moved aside first and restored if the final rename fails.
+func promoteStagingDir(stagingDir, targetDir string) error {
+	if _, err := os.Lstat(targetDir); err == nil {
+		backupDir := stagingDir + ".old"
+		if err := os.Rename(targetDir, backupDir); err != nil {
+			return fmt.Errorf("failed to move existing %s aside: %v", targetDir, err)
+		}
+		if err := os.Rename(stagingDir, targetDir); err != nil {
+			if restoreErr := os.Rename(backupDir, targetDir); restoreErr != nil {
+				logger.Log.WithError(restoreErr).Errorf("Failed to restore %s from %s", targetDir, backupDir)
+			}
+			return fmt.Errorf("failed to move fetched repository into %s: %v", targetDir, err)
+		}
+		if err := os.RemoveAll(backupDir); err != nil {
+			logger.Log.WithError(err).Warnf("Failed to remove previous contents of %s at %s", targetDir, backupDir)
+		}
+		return nil
+	}
+
+	if err := os.Rename(stagingDir, targetDir); err != nil {
+		return fmt.Errorf("failed to move fetched repository into %s: %v", targetDir, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// fetch/staging_test.go
+package fetch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+)
+
+const testCloneURL = "https://github.com/org/repo.git"
+
+func TestCheckTargetDir(t *testing.T) {
+	base := t.TempDir()
+
+	empty := filepath.Join(base, "empty")
+	if err := os.Mkdir(empty, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	unrelated := filepath.Join(base, "unrelated")
+	if err := os.MkdirAll(filepath.Join(unrelated, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(base, "file")
+	if err := os.WriteFile(file, []byte("data\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// cloneDir returns a repository whose origin is remoteURL
+	cloneDir := func(remoteURL string) string {
+		repo := newTestRepo(t)
+		commitFile(t, repo, "a.txt", "a\n")
+		if _, err := repo.CreateRemote(&config.RemoteConfig{Name: defaultRemote, URLs: []string{remoteURL}}); err != nil {
+			t.Fatal(err)
+		}
+		worktree, _ := repo.Worktree()
+		return worktree.Filesystem.Root()
+	}
+	sameClone := cloneDir(testCloneURL)
+	otherClone := cloneDir("https://github.com/org/other.git")
+
+	tests := []struct {
+		name    string
+		dir     string
+		force   bool
+		wantErr string
+	}{
+		{"missing", filepath.Join(base, "missing"), false, ""},
+		{"empty", empty, false, ""},
+		{"unrelated contents", unrelated, false, "is not empty and is not a clone"},
+		{"unrelated contents with force", unrelated, true, ""},
+		{"clone of the same URL", sameClone, false, ""},
+		{"clone of another URL", otherClone, false, "is not empty and is not a clone"},
+		{"clone of another URL with force", otherClone, true, ""},
+		{"file", file, false, "is not a directory"},
+		{"file with force", file, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTargetDir(tt.dir, testCloneURL, tt.force)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkTargetDir() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkTargetDir() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeMarker creates dir with a single file named name.
+func writeMarker(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dirEntries returns the names in dir.
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestNewStagingDir(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "nested", "target")
+	stagingDir, err := newStagingDir(target)
+	if err != nil {
+		t.Fatalf("newStagingDir() error = %v", err)
+	}
+	if filepath.Dir(stagingDir) != filepath.Dir(target) || !strings.HasPrefix(filepath.Base(stagingDir), ".target.staging-") {
+		t.Errorf("newStagingDir() = %s, want a hidden sibling of %s", stagingDir, target)
+	}
+	if names := dirEntries(t, stagingDir); len(names) != 0 {
+		t.Errorf("staging directory holds %v, want it empty", names)
+	}
+}
+
+func TestPromoteStagingDir(t *testing.T) {
+	t.Run("new target", func(t *testing.T) {
+		target := filepath.Join(t.TempDir(), "target")
+		stagingDir, err := newStagingDir(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeMarker(t, stagingDir, "new")
+		if err := promoteStagingDir(stagingDir, target); err != nil {
+			t.Fatalf("promoteStagingDir() error = %v", err)
+		}
+		if names := dirEntries(t, target); len(names) != 1 || names[0] != "new" {
+			t.Errorf("target holds %v, want the fetched contents", names)
+		}
+	})
+
+	t.Run("replaces the target", func(t *testing.T) {
+		target := filepath.Join(t.TempDir(), "target")
+		writeMarker(t, target, "old")
+		stagingDir, err := newStagingDir(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeMarker(t, stagingDir, "new")
+		if err := promoteStagingDir(stagingDir, target); err != nil {
+			t.Fatalf("promoteStagingDir() error = %v", err)
+		}
+		if names := dirEntries(t, target); len(names) != 1 || names[0] != "new" {
+			t.Errorf("target holds %v, want only the fetched contents", names)
+		}
+		if names := dirEntries(t, filepath.Dir(target)); len(names) != 1 {
+			t.Errorf("parent holds %v, want the staging and backup directories gone", names)
+		}
+	})
+
+	t.Run("restores the target when the rename fails", func(t *testing.T) {
+		target := filepath.Join(t.TempDir(), "target")
+		writeMarker(t, target, "old")
+		// A staging directory that has vanished makes the final rename fail
+		stagingDir := filepath.Join(filepath.Dir(target), ".target.staging-gone")
+		err := promoteStagingDir(stagingDir, target)
+		if err == nil || !strings.Contains(err.Error(), "failed to move fetched repository") {
+			t.Fatalf("promoteStagingDir() error = %v, want a rename failure", err)
+		}
+		if names := dirEntries(t, target); len(names) != 1 || names[0] != "old" {
+			t.Errorf("target holds %v, want the previous contents restored", names)
+		}
+		if _, err := os.Lstat(stagingDir + ".old"); !os.IsNotExist(err) {
+			t.Errorf("backup directory left behind: %v", err)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+// fetch/submodule.go
+package fetch
+
+import (
+	"fmt"
+	"mygitapp/logger"
+	"net/url"
+	"path"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+)
+
+// SubmoduleInfo describes a submodule checked out by a recursive fetch.
+type SubmoduleInfo struct {
+	Path   string          `json:"path"`                 // path relative to the top-level repository
+	URL    string          `json:"url"`                  // URL the submodule was cloned from
+	Commit string          `json:"commit"`               // commit the submodule is pinned to
+	Nested []SubmoduleInfo `json:"submodules,omitempty"` // submodules of the submodule
+}
+
+// updateSubmodules initializes and checks out the submodules of repo, recursing
+// up to depth levels. Relative submodule URLs are resolved against parentURL
+// and every URL gets the same authentication as a top-level clone would.
+// Reported paths are prefixed with prefix, the path of repo in the top-level
+// repository.
+func updateSubmodules(repo *git.Repository, parentURL, prefix string, depth int) ([]SubmoduleInfo, error) {
+	if depth <= 0 {
+		return nil, nil
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to get worktree")
+		return nil, fmt.Errorf("failed to get worktree: %v", err)
+	}
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to read submodules")
+		return nil, fmt.Errorf("failed to read submodules: %v", err)
+	}
+
+	var infos []SubmoduleInfo
+	for _, sub := range submodules {
+		cfg := sub.Config()
+		cfg.URL = resolveSubmoduleURL(parentURL, cfg.URL)
+		subPath := path.Join(prefix, cfg.Path)
+
+		err := sub.Update(&git.SubmoduleUpdateOptions{
+			Init:              true,
+			Auth:              authForURL(cfg.URL),
+			RecurseSubmodules: git.NoRecurseSubmodules,
+		})
+		if err != nil {
+			logger.Log.WithError(err).Errorf("Failed to update submodule %s", subPath)
+			return nil, fmt.Errorf("failed to update submodule %s: %v", subPath, err)
+		}
+
+		subRepo, err := sub.Repository()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open submodule %s: %v", subPath, err)
+		}
+		head, err := subRepo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve HEAD of submodule %s: %v", subPath, err)
+		}
+
+		nested, err := updateSubmodules(subRepo, cfg.URL, subPath, depth-1)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Log.Infof("Checked out submodule %s at %s", subPath, head.Hash())
+		infos = append(infos, SubmoduleInfo{Path: subPath, URL: cfg.URL, Commit: head.Hash().String(), Nested: nested})
+	}
+	return infos, nil
+}
+
+// resolveSubmoduleURL resolves a relative submodule URL ("./x" or "../x")
+// against the URL of the superproject, the way git does.
+func resolveSubmoduleURL(parentURL, subURL string) string {
+	if !strings.HasPrefix(subURL, "./") && !strings.HasPrefix(subURL, "../") {
+		return subURL
+	}
+
+	if !strings.Contains(parentURL, "://") {
+		if m := scpLikeURI.FindStringSubmatch(parentURL); m != nil {
+			return fmt.Sprintf("%s%s:%s", m[1], m[2], path.Join(m[3], subURL))
+		}
+	}
+
+	parsedURL, err := url.Parse(parentURL)
+	if err != nil {
+		return subURL
+	}
+	parsedURL.Path = path.Join(parsedURL.Path, subURL)
+	return parsedURL.String()
+}
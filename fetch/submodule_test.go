@@ -0,0 +1,130 @@
+// fetch/submodule_test.go
+package fetch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	tests := []struct {
+		parentURL, subURL, want string
+	}{
+		{"https://github.com/org/super.git", "https://github.com/org/lib.git", "https://github.com/org/lib.git"},
+		{"https://github.com/org/super.git", "git@github.com:org/lib.git", "git@github.com:org/lib.git"},
+		{"https://github.com/org/super.git", "../lib.git", "https://github.com/org/lib.git"},
+		{"https://github.com/org/super.git", "../../other/lib.git", "https://github.com/other/lib.git"},
+		{"https://github.com/org/super.git", "./lib.git", "https://github.com/org/super.git/lib.git"},
+		{"https://dev.azure.com/org/proj/_git/super", "../lib", "https://dev.azure.com/org/proj/_git/lib"},
+		{"ssh://git@git.example.com:2222/group/super.git", "../lib.git", "ssh://git@git.example.com:2222/group/lib.git"},
+		{"git@github.com:org/super.git", "../lib.git", "git@github.com:org/lib.git"},
+		{"git@github.com:org/super.git", "./lib.git", "git@github.com:org/super.git/lib.git"},
+		{"github.com:org/super.git", "../lib.git", "github.com:org/lib.git"},
+		{"/srv/git/super.git", "../lib.git", "/srv/git/lib.git"},
+	}
+	for _, tt := range tests {
+		if got := resolveSubmoduleURL(tt.parentURL, tt.subURL); got != tt.want {
+			t.Errorf("resolveSubmoduleURL(%q, %q) = %q, want %q", tt.parentURL, tt.subURL, got, tt.want)
+		}
+	}
+}
+
+// addSubmodule records a submodule at subPath in repo, pinned to commit and
+// cloned from url, and commits it.
+func addSubmodule(t *testing.T, repo *git.Repository, subPath, url string, commit plumbing.Hash) {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitmodules := "[submodule \"" + subPath + "\"]\n\tpath = " + subPath + "\n\turl = " + url + "\n"
+	if err := os.WriteFile(filepath.Join(worktree.Filesystem.Root(), ".gitmodules"), []byte(gitmodules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(".gitmodules"); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := idx.Add(subPath)
+	entry.Mode = filemode.Submodule
+	entry.Hash = commit
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Commit("add "+subPath, &git.CommitOptions{Author: testSignature}); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+}
+
+func TestUpdateSubmodulesNestedPaths(t *testing.T) {
+	base := t.TempDir()
+	initAt := func(name string) *git.Repository {
+		repo, err := git.PlainInit(filepath.Join(base, name), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}
+
+	// top -> libs/a -> deps/b -> vendor/c, with URLs relative to each superproject
+	c := initAt("c")
+	cHead := commitFile(t, c, "c.txt", "c\n")
+	b := initAt("b")
+	commitFile(t, b, "b.txt", "b\n")
+	addSubmodule(t, b, "vendor/c", "../c", cHead)
+	bHead, _ := b.Head()
+	a := initAt("a")
+	commitFile(t, a, "a.txt", "a\n")
+	addSubmodule(t, a, "deps/b", "../b", bHead.Hash())
+	aHead, _ := a.Head()
+	top := initAt("top")
+	commitFile(t, top, "top.txt", "top\n")
+	addSubmodule(t, top, "libs/a", "../a", aHead.Hash())
+
+	infos, err := updateSubmodules(top, filepath.Join(base, "top"), "", int(git.DefaultSubmoduleRecursionDepth))
+	if err != nil {
+		t.Fatalf("updateSubmodules() error = %v", err)
+	}
+	if len(infos) != 1 || len(infos[0].Nested) != 1 || len(infos[0].Nested[0].Nested) != 1 {
+		t.Fatalf("updateSubmodules() = %+v, want three nested levels", infos)
+	}
+
+	levels := []SubmoduleInfo{infos[0], infos[0].Nested[0], infos[0].Nested[0].Nested[0]}
+	want := []struct {
+		path, url string
+		commit    plumbing.Hash
+	}{
+		{"libs/a", filepath.Join(base, "a"), aHead.Hash()},
+		{"libs/a/deps/b", filepath.Join(base, "b"), bHead.Hash()},
+		{"libs/a/deps/b/vendor/c", filepath.Join(base, "c"), cHead},
+	}
+	for i, info := range levels {
+		if info.Path != want[i].path || info.URL != want[i].url || info.Commit != want[i].commit.String() {
+			t.Errorf("level %d = %+v, want %+v", i+1, info, want[i])
+		}
+	}
+
+	topWorktree, _ := top.Worktree()
+	data, err := os.ReadFile(filepath.Join(topWorktree.Filesystem.Root(), "libs/a/deps/b/vendor/c/c.txt"))
+	if err != nil || string(data) != "c\n" {
+		t.Errorf("c.txt = %q, %v, want the innermost submodule checked out", data, err)
+	}
+
+	// The depth limit stops the recursion
+	shallow, err := updateSubmodules(top, filepath.Join(base, "top"), "", 1)
+	if err != nil {
+		t.Fatalf("updateSubmodules() error = %v", err)
+	}
+	if len(shallow) != 1 || len(shallow[0].Nested) != 0 {
+		t.Errorf("updateSubmodules() with depth 1 = %+v", shallow)
+	}
+}
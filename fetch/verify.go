@@ -0,0 +1,207 @@
+// fetch/verify.go
+package fetch
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mygitapp/logger"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/crypto/ssh"
+)
+
+// VerifyMode selects how signature verification failures are handled.
+type VerifyMode string
+
+const (
+	// VerifyOff skips signature verification entirely.
+	VerifyOff VerifyMode = "off"
+	// VerifyWarn verifies signatures and logs a warning when verification fails.
+	VerifyWarn VerifyMode = "warn"
+	// VerifyRequire verifies signatures and fails the fetch when verification fails.
+	VerifyRequire VerifyMode = "require"
+)
+
+// ParseVerifyMode parses a verification policy name. An empty string means VerifyOff.
+func ParseVerifyMode(mode string) (VerifyMode, error) {
+	switch VerifyMode(strings.ToLower(mode)) {
+	case "", VerifyOff:
+		return VerifyOff, nil
+	case VerifyWarn:
+		return VerifyWarn, nil
+	case VerifyRequire:
+		return VerifyRequire, nil
+	}
+	return "", fmt.Errorf("unknown verification mode %q (expected off, warn or require)", mode)
+}
+
+// SignatureInfo describes the outcome of verifying a commit or tag signature.
+type SignatureInfo struct {
+	Object   string `json:"object"`           // "commit" or "tag"
+	Hash     string `json:"hash"`             // hash of the verified object
+	Type     string `json:"type,omitempty"`   // "gpg" or "ssh"
+	Signer   string `json:"signer,omitempty"` // signer identity from the keyring or allowed-signers file
+	Key      string `json:"key,omitempty"`    // GPG fingerprint or SSH key fingerprint
+	Verified bool   `json:"verified"`         // whether the signature was verified successfully
+	Error    string `json:"error,omitempty"`  // reason verification failed, if it did
+}
+
+// signedObject abstracts over commits and annotated tags for verification.
+type signedObject struct {
+	kind      string
+	hash      plumbing.Hash
+	signature string
+	payload   func(o plumbing.EncodedObject) error
+	verifyGPG func(armoredKeyRing string) (string, string, error)
+}
+
+// verifyCheckout verifies the signature of the annotated tag rev was resolved
+// through, or of the HEAD commit otherwise, according to opts.
+func verifyCheckout(repo *git.Repository, rev *Revision, opts Options) (*SignatureInfo, error) {
+	if opts.Verify == "" || opts.Verify == VerifyOff {
+		return nil, nil
+	}
+
+	obj, err := signedObjectFor(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SignatureInfo{Object: obj.kind, Hash: obj.hash.String()}
+	if err := verifyObject(obj, opts, info); err != nil {
+		info.Error = err.Error()
+		if opts.Verify == VerifyRequire {
+			logger.Log.WithError(err).Errorf("Signature verification failed for %s %s", obj.kind, obj.hash)
+			return info, fmt.Errorf("signature verification failed for %s %s: %v", obj.kind, obj.hash, err)
+		}
+		logger.Log.WithError(err).Warnf("Signature verification failed for %s %s", obj.kind, obj.hash)
+		return info, nil
+	}
+
+	info.Verified = true
+	logger.Log.Infof("Verified %s signature on %s %s by %s", info.Type, obj.kind, obj.hash, info.Signer)
+	return info, nil
+}
+
+// signedObjectFor returns the object whose signature guards the checkout.
+func signedObjectFor(repo *git.Repository, rev *Revision) (*signedObject, error) {
+	if rev != nil && rev.Tag != nil {
+		tag := rev.Tag
+		return &signedObject{
+			kind:      "tag",
+			hash:      tag.Hash,
+			signature: tag.PGPSignature,
+			payload:   tag.EncodeWithoutSignature,
+			verifyGPG: func(keyring string) (string, string, error) {
+				return gpgIdentity(tag.Verify(keyring))
+			},
+		}, nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %v", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD commit: %v", err)
+	}
+	return &signedObject{
+		kind:      "commit",
+		hash:      commit.Hash,
+		signature: commit.PGPSignature,
+		payload:   commit.EncodeWithoutSignature,
+		verifyGPG: func(keyring string) (string, string, error) {
+			return gpgIdentity(commit.Verify(keyring))
+		},
+	}, nil
+}
+
+// verifyObject checks the signature of obj and fills in the signer details of info.
+func verifyObject(obj *signedObject, opts Options, info *SignatureInfo) error {
+	signature := strings.TrimSpace(obj.signature)
+	switch {
+	case signature == "":
+		return fmt.Errorf("%s is not signed", obj.kind)
+	case strings.HasPrefix(signature, sshSigBeginArmor):
+		info.Type = "ssh"
+		return verifySSHObject(obj, opts, info)
+	case strings.HasPrefix(signature, "-----BEGIN PGP"):
+		info.Type = "gpg"
+		return verifyGPGObject(obj, opts, info)
+	}
+	return fmt.Errorf("unsupported signature format")
+}
+
+// verifyGPGObject verifies an OpenPGP signature against the configured armored keyring.
+func verifyGPGObject(obj *signedObject, opts Options, info *SignatureInfo) error {
+	if opts.Keyring == "" {
+		return fmt.Errorf("no GPG keyring configured")
+	}
+	keyring, err := os.ReadFile(opts.Keyring)
+	if err != nil {
+		return fmt.Errorf("failed to read GPG keyring: %v", err)
+	}
+
+	signer, fingerprint, err := obj.verifyGPG(string(keyring))
+	if err != nil {
+		return err
+	}
+	info.Signer = signer
+	info.Key = fingerprint
+	return nil
+}
+
+// verifySSHObject verifies an SSH signature against the configured allowed-signers file.
+func verifySSHObject(obj *signedObject, opts Options, info *SignatureInfo) error {
+	if opts.AllowedSigners == "" {
+		return fmt.Errorf("no SSH allowed signers file configured")
+	}
+	signers, err := loadAllowedSigners(opts.AllowedSigners)
+	if err != nil {
+		return err
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := obj.payload(encoded); err != nil {
+		return fmt.Errorf("failed to encode %s: %v", obj.kind, err)
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", obj.kind, err)
+	}
+	message, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", obj.kind, err)
+	}
+
+	key, err := verifySSHSignature(obj.signature, message)
+	if err != nil {
+		return err
+	}
+	info.Key = ssh.FingerprintSHA256(key)
+
+	signer := findAllowedSigner(signers, key)
+	if signer == nil {
+		return fmt.Errorf("key %s is not an allowed signer", info.Key)
+	}
+	info.Signer = strings.Join(signer.Principals, ",")
+	return nil
+}
+
+// gpgIdentity extracts the primary identity and fingerprint of a verified OpenPGP entity.
+func gpgIdentity(entity *openpgp.Entity, err error) (string, string, error) {
+	if err != nil {
+		return "", "", err
+	}
+	fingerprint := strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint))
+	if identity := entity.PrimaryIdentity(); identity != nil {
+		return identity.Name, fingerprint, nil
+	}
+	return "", fingerprint, nil
+}
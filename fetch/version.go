@@ -0,0 +1,146 @@
+// fetch/version.go
+package fetch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/mod/semver"
+)
+
+// latestSelector selects the highest semantic version tag.
+const latestSelector = "latest"
+
+// versionSelectorPattern matches "latest" and caret or tilde constraints such as ^1.4 or ~2.0.
+var versionSelectorPattern = regexp.MustCompile(`^(latest|[\^~]v?\d+(\.\d+){0,2})$`)
+
+// IsVersionSelector reports whether spec is a version selector: "latest" or a
+// caret (^1.4) or tilde (~2.0) constraint.
+func IsVersionSelector(spec string) bool {
+	return versionSelectorPattern.MatchString(spec)
+}
+
+// ResolveSelector resolves spec like ResolveRevision, except that version
+// selectors are resolved to the highest matching semantic version tag. A
+// selector that names an existing reference (e.g. a branch called "latest")
+// resolves to that reference instead. Pre-release versions are only
+// considered if prerelease is set.
+func ResolveSelector(repo *git.Repository, spec string, prerelease bool) (*Revision, error) {
+	if !IsVersionSelector(spec) {
+		return ResolveRevision(repo, spec)
+	}
+	for _, name := range candidateRefNames(spec) {
+		if _, err := repo.Reference(name, false); err == nil {
+			return ResolveRevision(repo, spec)
+		}
+	}
+
+	tag, err := latestMatchingTag(repo, spec, prerelease)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := ResolveRevision(repo, tag.String())
+	if err != nil {
+		return nil, err
+	}
+	rev.Spec = spec
+	return rev, nil
+}
+
+// latestMatchingTag returns the tag with the highest semantic version that
+// satisfies selector. Tags are read as versions with or without a leading "v".
+func latestMatchingTag(repo *git.Repository, selector string, prerelease bool) (plumbing.ReferenceName, error) {
+	lower, upper := versionRange(selector)
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return "", fmt.Errorf("failed to list tags: %v", err)
+	}
+	var best plumbing.ReferenceName
+	var bestVersion string
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		version := tagVersion(ref.Name().Short())
+		switch {
+		case version == "":
+			return nil
+		case semver.Prerelease(version) != "" && !prerelease:
+			return nil
+		case lower != "" && semver.Compare(version, lower) < 0:
+			return nil
+		// Pre-releases of the upper bound are excluded along with the release
+		case upper != "" && semver.Compare(versionCore(version), upper) >= 0:
+			return nil
+		}
+		cmp := semver.Compare(version, bestVersion)
+		if best == "" || cmp > 0 || (cmp == 0 && ref.Name() < best) {
+			best, bestVersion = ref.Name(), version
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list tags: %v", err)
+	}
+	if best == "" {
+		return "", fmt.Errorf("no release tag matches %q", selector)
+	}
+	return best, nil
+}
+
+// tagVersion returns the tag name as a semantic version with a leading "v",
+// or "" if it isn't one.
+func tagVersion(tag string) string {
+	version := tag
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !semver.IsValid(version) {
+		return ""
+	}
+	return version
+}
+
+// versionCore strips the pre-release and build suffixes from version.
+func versionCore(version string) string {
+	version = strings.TrimSuffix(version, semver.Build(version))
+	return strings.TrimSuffix(version, semver.Prerelease(version))
+}
+
+// versionRange returns the inclusive lower and exclusive upper bound of a
+// selector, following npm semantics: ^1.4 is >=1.4.0 <2.0.0, ^0.3 is
+// >=0.3.0 <0.4.0 and ~2.0 is >=2.0.0 <2.1.0. "latest" has no bounds.
+func versionRange(selector string) (string, string) {
+	if selector == latestSelector {
+		return "", ""
+	}
+	var parts []int
+	for _, part := range strings.Split(strings.TrimPrefix(selector[1:], "v"), ".") {
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+	for len(parts) < 3 {
+		parts = append(parts, 0)
+	}
+	specified := len(strings.Split(selector[1:], "."))
+	major, minor, patch := parts[0], parts[1], parts[2]
+	lower := fmt.Sprintf("v%d.%d.%d", major, minor, patch)
+
+	if selector[0] == '~' {
+		if specified == 1 {
+			return lower, fmt.Sprintf("v%d.0.0", major+1)
+		}
+		return lower, fmt.Sprintf("v%d.%d.0", major, minor+1)
+	}
+
+	// Caret: the leftmost non-zero component given may not change
+	switch {
+	case major > 0 || specified == 1:
+		return lower, fmt.Sprintf("v%d.0.0", major+1)
+	case minor > 0 || specified == 2:
+		return lower, fmt.Sprintf("v0.%d.0", minor+1)
+	}
+	return lower, fmt.Sprintf("v0.0.%d", patch+1)
+}
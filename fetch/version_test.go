@@ -0,0 +1,109 @@
+// fetch/version_test.go
+package fetch
+
+import "testing"
+
+func TestVersionRange(t *testing.T) {
+	tests := []struct {
+		selector, lower, upper string
+	}{
+		{"latest", "", ""},
+		{"^1.4", "v1.4.0", "v2.0.0"},
+		{"^1.4.2", "v1.4.2", "v2.0.0"},
+		{"^v1", "v1.0.0", "v2.0.0"},
+		{"^0.3", "v0.3.0", "v0.4.0"},
+		{"^0.3.1", "v0.3.1", "v0.4.0"},
+		{"^0.0.5", "v0.0.5", "v0.0.6"},
+		{"^0", "v0.0.0", "v1.0.0"},
+		{"^0.0", "v0.0.0", "v0.1.0"},
+		{"~2.0", "v2.0.0", "v2.1.0"},
+		{"~2.0.3", "v2.0.3", "v2.1.0"},
+		{"~2", "v2.0.0", "v3.0.0"},
+	}
+	for _, tt := range tests {
+		lower, upper := versionRange(tt.selector)
+		if lower != tt.lower || upper != tt.upper {
+			t.Errorf("versionRange(%q) = %q, %q, want %q, %q", tt.selector, lower, upper, tt.lower, tt.upper)
+		}
+	}
+}
+
+func TestIsVersionSelector(t *testing.T) {
+	tests := map[string]bool{
+		"latest":   true,
+		"^1.4":     true,
+		"~v2.0.1":  true,
+		"^1.2.3.4": false,
+		"1.4":      false,
+		"main":     false,
+		"^":        false,
+	}
+	for spec, want := range tests {
+		if got := IsVersionSelector(spec); got != want {
+			t.Errorf("IsVersionSelector(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
+
+func TestLatestMatchingTag(t *testing.T) {
+	repo := newTestRepo(t)
+	commit := commitFile(t, repo, "a.txt", "a\n")
+	for _, tag := range []string{"v0.3.0", "v0.3.4", "v0.4.0", "1.3.9", "v1.4.0", "v1.5.2", "v1.6.0-rc.1", "v2.0.0-beta.1", "v2.0.0", "v2.0.7", "v2.1.0", "nightly"} {
+		setRef(t, repo, "refs/tags/"+tag, commit)
+	}
+
+	tests := []struct {
+		selector   string
+		prerelease bool
+		want       string
+	}{
+		{"latest", false, "refs/tags/v2.1.0"},
+		{"^1.4", false, "refs/tags/v1.5.2"},
+		{"^1.4", true, "refs/tags/v1.6.0-rc.1"},
+		{"^1", false, "refs/tags/v1.5.2"},
+		{"^0.3", false, "refs/tags/v0.3.4"},
+		{"~2.0", false, "refs/tags/v2.0.7"},
+		{"~1.3", false, "refs/tags/1.3.9"},
+		{"^3", false, ""},
+	}
+	for _, tt := range tests {
+		got, err := latestMatchingTag(repo, tt.selector, tt.prerelease)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("latestMatchingTag(%q) = %s, want an error", tt.selector, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("latestMatchingTag(%q) error = %v", tt.selector, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("latestMatchingTag(%q, %v) = %s, want %s", tt.selector, tt.prerelease, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSelectorPrefersReferences(t *testing.T) {
+	repo := newTestRepo(t)
+	first := commitFile(t, repo, "a.txt", "a\n")
+	second := commitFile(t, repo, "a.txt", "b\n")
+	setRef(t, repo, "refs/tags/v1.0.0", second)
+	setRef(t, repo, "refs/heads/latest", first)
+
+	rev, err := ResolveSelector(repo, "latest", false)
+	if err != nil {
+		t.Fatalf("ResolveSelector() error = %v", err)
+	}
+	if rev.Name != "refs/heads/latest" || rev.Hash != first {
+		t.Errorf("ResolveSelector(latest) = %s %s, want the latest branch", rev.Name, rev.Hash)
+	}
+
+	rev, err = ResolveSelector(repo, "^1", false)
+	if err != nil {
+		t.Fatalf("ResolveSelector() error = %v", err)
+	}
+	if rev.Name != "refs/tags/v1.0.0" || rev.Hash != second || rev.Spec != "^1" {
+		t.Errorf("ResolveSelector(^1) = %+v", rev)
+	}
+}
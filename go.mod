@@ -3,15 +3,18 @@ module mygitapp
 go 1.22.5
 
 require (
+	github.com/ProtonMail/go-crypto v1.0.0
+	github.com/bmatcuk/doublestar/v4 v4.10.2
 	github.com/go-git/go-git/v5 v5.12.0
 	github.com/sirupsen/logrus v1.9.3
 	golang.org/x/crypto v0.21.0
+	golang.org/x/mod v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	dario.cat/mergo v1.0.0 // indirect
 	github.com/Microsoft/go-winio v0.6.1 // indirect
-	github.com/ProtonMail/go-crypto v1.0.0 // indirect
 	github.com/cloudflare/circl v1.3.7 // indirect
 	github.com/cyphar/filepath-securejoin v0.2.4 // indirect
 	github.com/emirpasic/gods v1.18.1 // indirect
@@ -24,7 +27,6 @@ require (
 	github.com/sergi/go-diff v1.3.2-0.20230802210424-5b0b94c5c0d3 // indirect
 	github.com/skeema/knownhosts v1.2.2 // indirect
 	github.com/xanzy/ssh-agent v0.3.3 // indirect
-	golang.org/x/mod v0.12.0 // indirect
 	golang.org/x/net v0.22.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
 	golang.org/x/tools v0.13.0 // indirect
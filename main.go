@@ -3,6 +3,7 @@ package main
 
 import (
 	"mygitapp/diff"
+	"mygitapp/export"
 	"mygitapp/fetch"
 	"mygitapp/logger"
 	"os"
@@ -26,26 +27,19 @@ func main() {
 
 	switch command {
 	case "fetch":
-		// Allow optional targetDir
-		if len(os.Args) < 3 || len(os.Args) > 4 {
-			logger.Log.Error("Invalid arguments for fetch")
-			logger.Log.Error("Usage: fetch <git URI> [targetDir]")
-			return
-		}
-		gitRepoURI := os.Args[2]
-		targetDir := ""
-		if len(os.Args) == 4 {
-			targetDir = os.Args[3]
-		}
-		if err := fetch.CloneRepository(gitRepoURI, targetDir); err != nil {
-			logger.Log.WithError(err).Error("Fetch operation failed")
-		} else {
-			logger.Log.Info("Fetch operation completed successfully")
-		}
+		fetch.RunFetch(os.Args[2:])
 	case "diff":
 		diff.RunDiff(os.Args[2:])
+	case "export":
+		export.RunExport(os.Args[2:])
+	case "refs":
+		fetch.RunRefs(os.Args[2:])
+	case "mirror":
+		fetch.RunMirror(os.Args[2:])
+	case "discover":
+		fetch.RunDiscover(os.Args[2:])
 	default:
 		logger.Log.Error("Unknown command")
-		logger.Log.Error("Available commands: fetch, diff")
+		logger.Log.Error("Available commands: fetch, diff, export, refs, mirror, discover")
 	}
 }